@@ -1,76 +1,223 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/neil-and-void/hurl/src"
 )
 
+const VERSION = "v0.4.2"
+
 func main() {
+	// subcommands have their own flags so are handled before the request flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(src.RunFmt(os.Args[2:]))
+		case "lint":
+			os.Exit(src.RunLint(os.Args[2:]))
+		case "lsp":
+			os.Exit(src.RunLSP(os.Args[2:]))
+		case "import":
+			os.Exit(src.RunImport(os.Args[2:]))
+		case "export":
+			os.Exit(src.RunExport(os.Args[2:]))
+		}
+	}
+
 	config, err := src.InitConfig()
 
 	if config.Version {
-		fmt.Println("v0.4.2")
+		fmt.Println(VERSION)
 		os.Exit(0)
 	}
 
 	if err != nil {
-		fmt.Printf("hurl: %s\n", err.Error())
-		os.Exit(1)
+		// the config isn't returned when it's invalid but the flags are parsed
+		config.OutputFormat = flag.Lookup("output-format").Value.String()
+		exitWithError(config, nil, err)
 	}
 
+	src.ConfigureColor(config.Color)
+
 	hurlOutput := src.HurlOutput{Config: config}
+	src.CollectWarnings = config.OutputFormat == "json"
 
 	if len(os.Args) < 2 {
-		fmt.Println("hurl: no hurl file provided")
-		os.Exit(1)
+		exitWithError(config, nil, errors.New("no hurl file provided"))
 	}
 
 	hurlFilePath := os.Args[len(os.Args)-1]
+	hurlOutput.HurlFilePath = hurlFilePath
 
 	_, err = os.Stat(hurlFilePath)
 	if err != nil {
-		fmt.Printf("hurl: file does not exist: %s\n", hurlFilePath)
-		os.Exit(1)
+		exitWithError(config, nil, fmt.Errorf("file does not exist: %s", hurlFilePath))
 	}
 
-	f, err := os.OpenFile(hurlFilePath, os.O_RDONLY, os.ModePerm)
+	hurlFileBytes, err := os.ReadFile(hurlFilePath)
 	if err != nil {
-		fmt.Printf("hurl: %s\n", err.Error())
-		os.Exit(1)
+		exitWithError(config, nil, err)
 	}
 
-	hurlFile, err := src.ParseHurlFile(f)
+	hurlFile, err := src.ParseHurlFile(bytes.NewReader(hurlFileBytes))
 	if err != nil {
-		fmt.Printf("hurl: %s\n", err.Error())
-		os.Exit(1)
+		// the syntax tree is only used to say where the error is, templates
+		// aren't filled in so it isn't what decides if the file is sent
+		if _, astErr := src.ParseHurlAST(hurlFileBytes); astErr != nil {
+			err = fmt.Errorf("%s:%s", hurlFilePath, astErr.Error())
+		}
+		exitWithError(config, nil, err)
+	}
+
+	if config.OutputFormat == "json" && (hurlFile.IsWebSocket() || hurlFile.IsGRPC()) {
+		exitWithError(config, hurlFile, errors.New("-output-format json is only supported for HTTP requests"))
+	}
+
+	if config.HarPath != "" && (hurlFile.IsWebSocket() || hurlFile.IsGRPC()) {
+		exitWithError(config, hurlFile, errors.New("-har is only supported for HTTP requests"))
+	}
+
+	if hurlFile.IsWebSocket() {
+		err = hurlOutput.RunWebSocket(hurlFile)
+		if err != nil {
+			exitWithError(config, hurlFile, err)
+		}
+		os.Exit(0)
 	}
-	f.Close()
+
+	if hurlFile.IsGRPC() {
+		err = src.StartPager(config.Pager)
+		if err != nil {
+			exitWithError(config, hurlFile, err)
+		}
+
+		err = sendGRPCRequest(hurlOutput, hurlFile)
+		src.ClosePager()
+		if err != nil {
+			exitWithError(config, hurlFile, err)
+		}
+		os.Exit(0)
+	}
+
+	if config.Trace {
+		err = src.OpenTrace(config.TraceFile)
+		if err != nil {
+			exitWithError(config, hurlFile, err)
+		}
+	}
+
+	if config.HarPath != "" {
+		err = src.OpenHar(config.HarPath, strings.TrimPrefix(VERSION, "v"))
+		if err != nil {
+			exitWithError(config, hurlFile, err)
+		}
+	}
+
+	hurlOutput.GraphQL = hurlFile.IsGraphQL
 
 	req, err := hurlFile.NewRequest()
 	if err != nil {
-		fmt.Printf("hurl: %s\n", err.Error())
+		exitWithError(config, hurlFile, err)
+	}
+
+	if config.OutputFormat == "json" {
+		err = hurlOutput.OutputJson(src.NewHttpClient(config), req)
+		if err != nil {
+			exitWithError(config, hurlFile, err)
+		}
+		os.Exit(0)
+	}
+
+	err = src.StartPager(config.Pager)
+	if err != nil {
+		exitWithError(config, hurlFile, err)
+	}
+
+	// the pager is closed before errors are printed so they come after the
+	// output instead of being hidden behind it
+	err = sendHttpRequest(hurlOutput, hurlFile, req)
+	src.ClosePager()
+	src.CloseTrace()
+	if harErr := src.CloseHar(); err == nil {
+		err = harErr
+	}
+	if err != nil {
+		exitWithError(config, hurlFile, err)
+	}
+}
+
+// exitWithError prints err and exits, with -output-format json it's printed as
+// the error of the JSON document so the output is always a JSON document.
+// hurlFile is nil when the request file couldn't be parsed
+func exitWithError(config src.HurlConfig, hurlFile *src.HurlFile, err error) {
+	if config.OutputFormat == "json" && !errors.Is(err, src.ErrReportedInJson) {
+		err = src.OutputJsonError(hurlFile, err)
+	}
+
+	// already in the JSON document
+	if errors.Is(err, src.ErrReportedInJson) {
 		os.Exit(1)
 	}
 
-	if config.Verbose {
+	fmt.Fprintf(os.Stderr, "hurl: %s\n", err.Error())
+	os.Exit(1)
+}
+
+func sendHttpRequest(hurlOutput src.HurlOutput, hurlFile *src.HurlFile, req *http.Request) error {
+	var err error
+
+	if hurlOutput.Config.Continue {
+		hurlOutput.ResumeOffset, err = src.PrepareResume(req, hurlOutput.Config.BodyOutputPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if hurlOutput.Config.Verbose {
 		err = hurlOutput.OutputRequest(hurlFile, *req)
 		if err != nil {
-			fmt.Printf("hurl: %s\n", err.Error())
-			os.Exit(1)
+			return err
 		}
 	}
 
-	res, err := src.WaitForHttpRequest(req)
+	res, err := src.WaitForHttpRequest(src.NewHttpClient(hurlOutput.Config), req)
 	if err != nil {
-		fmt.Printf("hurl: %s\n", err.Error())
-		os.Exit(1)
+		return err
+	}
+
+	return hurlOutput.OutputResponse(*res)
+}
+
+func sendGRPCRequest(hurlOutput src.HurlOutput, hurlFile *src.HurlFile) error {
+	call, err := hurlFile.NewGRPCCall(hurlOutput.Config.Protoset, hurlOutput.Config.GRPCProtocol)
+	if err != nil {
+		return err
 	}
 
-	err = hurlOutput.OutputResponse(*res)
+	req, err := call.NewRequest(hurlFile)
 	if err != nil {
-		fmt.Printf("hurl: %s\n", err.Error())
-		os.Exit(1)
+		return err
 	}
+
+	if hurlOutput.Config.Verbose {
+		err = hurlOutput.OutputRequest(hurlFile, *req)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := src.WaitForHttpRequest(call.Client(hurlFile.URL, hurlOutput.Config), req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return hurlOutput.OutputGRPCResponse(*res, call)
 }
@@ -0,0 +1,236 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Pos is a 1 based line and column in a request file
+type Pos struct {
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// SyntaxError is a problem with a request file at a position in it
+type SyntaxError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+type RequestLineNode struct {
+	Method    string
+	URL       string
+	MethodPos Pos
+	URLPos    Pos
+}
+
+type HeaderNode struct {
+	Name     string
+	Value    string
+	NamePos  Pos
+	ValuePos Pos
+}
+
+// BodyLineNode is a line of a body as it was written
+type BodyLineNode struct {
+	Text string
+	Pos  Pos
+}
+
+// HurlAST is a request file as it was written, before variables are
+// interpolated and files are read, with the position of everything in it.
+// ParseHurlFile turns the same file into a request
+type HurlAST struct {
+	RequestLine RequestLineNode
+	Headers     []HeaderNode
+	Body        []BodyLineNode
+
+	// a blank line after the headers starts the body, even an empty one
+	HasBody bool
+}
+
+// splitLines splits a file into lines the way bufio.Scanner does, without
+// line endings and without an empty line after the last newline
+func splitLines(src []byte) []string {
+	text := strings.TrimSuffix(string(src), "\n")
+	if text == "" {
+		return []string{}
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	return lines
+}
+
+// leadingSpace is the number of bytes of whitespace a line starts with
+func leadingSpace(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// splitRequestLine splits the request line on spaces, template variables are
+// interpolated before the line is split so spaces inside {{ }} don't count
+func splitRequestLine(line string) []string {
+	components := []string{}
+	component := strings.Builder{}
+
+	inTemplate := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "{{"):
+			inTemplate = true
+		case strings.HasPrefix(line[i:], "}}"):
+			inTemplate = false
+		case line[i] == ' ' && !inTemplate:
+			components = append(components, component.String())
+			component.Reset()
+			continue
+		}
+
+		component.WriteByte(line[i])
+	}
+
+	return append(components, component.String())
+}
+
+// ParseHurlAST parses the structure of a request file, the errors are the
+// ones ParseHurlFile would run into but with where they are in the file
+func ParseHurlAST(src []byte) (*HurlAST, error) {
+	ast := &HurlAST{}
+	lines := splitLines(src)
+
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+		return nil, &SyntaxError{Pos{1, 1}, "missing request line"}
+	}
+
+	//=== request line ===//
+	requestLine := strings.TrimSpace(lines[0])
+	requestLineCol := leadingSpace(lines[0]) + 1
+	requestLineComponents := splitRequestLine(requestLine)
+
+	if len(requestLineComponents) > 2 {
+		col := requestLineCol + len(requestLineComponents[METHOD]) + 1 + len(requestLineComponents[URL])
+		return nil, &SyntaxError{Pos{1, col}, "too many request line components, expected [method] [url]"}
+	}
+	if len(requestLineComponents) < 2 {
+		return nil, &SyntaxError{Pos{1, requestLineCol}, "not enough request line components, expected [method] [url]"}
+	}
+
+	method := requestLineComponents[METHOD]
+	if !isValidMethod(method) {
+		return nil, &SyntaxError{Pos{1, requestLineCol}, fmt.Sprintf("invalid method: %s", method)}
+	}
+
+	ast.RequestLine = RequestLineNode{
+		Method:    method,
+		URL:       requestLineComponents[URL],
+		MethodPos: Pos{1, requestLineCol},
+		URLPos:    Pos{1, requestLineCol + len(method) + 1},
+	}
+
+	//=== headers ===//
+	i := 1
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		name, value, found := strings.Cut(lines[i], ":")
+		if !found {
+			return nil, &SyntaxError{Pos{i + 1, leadingSpace(lines[i]) + 1}, fmt.Sprintf("header is malformed, expected [header]: [value]: `%s`", lines[i])}
+		}
+
+		valueCol := len(name) + 1 + leadingSpace(value) + 1
+
+		ast.Headers = append(ast.Headers, HeaderNode{
+			Name:     strings.TrimSpace(name),
+			Value:    strings.TrimSpace(value),
+			NamePos:  Pos{i + 1, leadingSpace(name) + 1},
+			ValuePos: Pos{i + 1, valueCol},
+		})
+	}
+
+	//=== body ===//
+	if i >= len(lines) {
+		return ast, nil
+	}
+
+	ast.HasBody = true
+	for i++; i < len(lines); i++ {
+		ast.Body = append(ast.Body, BodyLineNode{Text: lines[i], Pos: Pos{i + 1, 1}})
+	}
+
+	return ast, nil
+}
+
+// Header is the value of the last header with this name, header names are
+// case insensitive
+func (a *HurlAST) Header(name string) (HeaderNode, bool) {
+	found := false
+	header := HeaderNode{}
+
+	for _, h := range a.Headers {
+		if strings.EqualFold(h.Name, name) {
+			header = h
+			found = true
+		}
+	}
+
+	return header, found
+}
+
+// MediaType is the media type of the Content-Type header without parameters
+func (a *HurlAST) MediaType() string {
+	header, exists := a.Header("Content-Type")
+	if !exists {
+		return ""
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Value)
+	if err != nil {
+		return strings.ToLower(header.Value)
+	}
+
+	return mediaType
+}
+
+// IsMultipart is whether ParseHurlFile reads the body as multipart lines,
+// which it only does when the Content-Type is exactly multipart/form-data
+func (a *HurlAST) IsMultipart() bool {
+	header, exists := a.Header("Content-Type")
+	return exists && header.Value == "multipart/form-data"
+}
+
+// BodyText is the body the way ParseHurlFile reads it, every line followed
+// by a newline
+func (a *HurlAST) BodyText() []byte {
+	buffer := bytes.Buffer{}
+	for _, line := range a.Body {
+		buffer.WriteString(line.Text)
+		buffer.WriteByte('\n')
+	}
+
+	return buffer.Bytes()
+}
+
+// IsGraphQL is whether the body is a GraphQL query marked by a GRAPHQL line
+func (a *HurlAST) IsGraphQL() bool {
+	lines := []string{}
+	for _, line := range a.Body {
+		lines = append(lines, line.Text)
+	}
+
+	return isGraphQLBody(lines)
+}
+
+func (a *HurlAST) IsWebSocket() bool {
+	return a.RequestLine.Method == "WS" || strings.HasPrefix(a.RequestLine.URL, "ws://") || strings.HasPrefix(a.RequestLine.URL, "wss://")
+}
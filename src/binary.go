@@ -0,0 +1,140 @@
+package src
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// filename used with -O when neither Content-Disposition nor the URL has one
+const DEFAULT_REMOTE_NAME = "hurl-response"
+
+var binaryMediaTypePrefixes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"font/",
+	"application/octet-stream",
+	"application/pdf",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-tar",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/vnd.",
+	"application/msword",
+	"application/wasm",
+	"application/x-protobuf",
+	"application/protobuf",
+	"application/grpc",
+}
+
+func isTextMediaType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/x-www-form-urlencoded" ||
+		lexerForMediaType(mediaType) != ""
+}
+
+// IsBinary reports whether a body would garble the terminal if printed, going
+// by the media type and falling back to sniffing the content
+func IsBinary(body []byte, mediaType string) bool {
+	// svg and friends are text even though they are images
+	if isTextMediaType(mediaType) {
+		return false
+	}
+
+	for _, prefix := range binaryMediaTypePrefixes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+
+	sniff := body
+	if len(sniff) > 512 {
+		sniff = sniff[:512]
+	}
+
+	if bytes.IndexByte(sniff, 0) != -1 {
+		return true
+	}
+
+	// a multibyte character may have been cut off at the end of the sniff
+	for i := 0; i < utf8.UTFMax && len(sniff) > 0 && !utf8.Valid(sniff); i++ {
+		sniff = sniff[:len(sniff)-1]
+	}
+
+	return !utf8.Valid(sniff)
+}
+
+// FormatBinarySummary describes a binary body instead of printing it, with a
+// hex dump of the first hexDumpLength bytes if asked for
+func FormatBinarySummary(body []byte, mediaType string, hexDumpLength int) []byte {
+	buffer := bytes.Buffer{}
+
+	title := color.New(color.FgBlack, color.BgWhite).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if mediaType == "" {
+		mediaType = "unknown"
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+
+	buffer.WriteString(fmt.Sprintf("%s\n", title(" binary body not shown ")))
+	buffer.WriteString(fmt.Sprintf("%s: %s (detected %s)\n", yellow("type"), mediaType, sniffed))
+	buffer.WriteString(fmt.Sprintf("%s: %s (%d bytes)\n", yellow("size"), formatBytes(int64(len(body))), len(body)))
+	buffer.WriteString(fmt.Sprintf("%s: %x\n", yellow("sha256"), sha256.Sum256(body)))
+
+	if hexDumpLength > 0 {
+		dumped := body
+		if len(dumped) > hexDumpLength {
+			dumped = dumped[:hexDumpLength]
+		}
+
+		buffer.WriteString("\n")
+		buffer.WriteString(hex.Dump(dumped))
+	} else {
+		buffer.WriteString("\nuse -hex=N to dump the first N bytes or -O to save the body\n")
+	}
+
+	return buffer.Bytes()
+}
+
+// RemoteName picks a filename for -O from the Content-Disposition header,
+// then the last segment of the URL path. Only the base name is used so a
+// server can't write outside the current directory
+func RemoteName(res http.Response) string {
+	_, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition"))
+	if err == nil {
+		// mime decodes filename* into filename
+		if name := sanitizeFileName(params["filename"]); name != "" {
+			return name
+		}
+	}
+
+	if res.Request != nil && res.Request.URL != nil {
+		if name := sanitizeFileName(path.Base(res.Request.URL.Path)); name != "" {
+			return name
+		}
+	}
+
+	return DEFAULT_REMOTE_NAME
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." || strings.HasPrefix(name, ".") {
+		return ""
+	}
+
+	return name
+}
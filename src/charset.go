@@ -0,0 +1,62 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// DecodeCharset transcodes a body in the charset from its Content-Type to
+// UTF-8 for display. Charsets are looked up by their WHATWG names so labels
+// like ISO-8859-1 decode as windows-1252 the same way browsers do. The body is
+// returned untouched if the charset is unknown
+func DecodeCharset(body []byte, charset string) []byte {
+	if charset == "" || len(body) == 0 {
+		return body
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		PrintWarning(fmt.Errorf("unknown charset %q, showing the body as UTF-8", charset))
+		return body
+	}
+
+	name, _ := htmlindex.Name(encoding)
+	if name == "utf-8" {
+		if !utf8.Valid(body) {
+			PrintWarning(fmt.Errorf("body declared as %s isn't valid UTF-8", charset))
+		}
+		return body
+	}
+
+	// text that happens to be valid UTF-8 with multi byte characters in it
+	// is almost certainly UTF-8 mislabelled as another charset
+	if !isASCII(body) && utf8.Valid(body) && !strings.HasPrefix(name, "utf-16") {
+		PrintWarning(fmt.Errorf("body declared as %s looks like UTF-8", charset))
+	}
+
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		PrintWarning(fmt.Errorf("body isn't valid %s: %w", charset, err))
+		return body
+	}
+
+	if bytes.ContainsRune(decoded, utf8.RuneError) && !bytes.ContainsRune(body, utf8.RuneError) {
+		PrintWarning(fmt.Errorf("body isn't valid %s, invalid characters were replaced", charset))
+	}
+
+	return decoded
+}
+
+func isASCII(body []byte) bool {
+	for _, b := range body {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
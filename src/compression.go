@@ -0,0 +1,123 @@
+package src
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fatih/color"
+	"github.com/klauspost/compress/zstd"
+)
+
+// sent unless the request file has its own Accept-Encoding, which can name
+// a single encoding to force it or identity to turn compression off
+const DEFAULT_ACCEPT_ENCODING = "gzip, deflate, br, zstd"
+
+// setAcceptEncoding asks for every encoding hurl can decode. Setting the
+// header also stops Go's transport from quietly asking for and removing gzip
+// so the encoding the server picked can be shown
+func setAcceptEncoding(header http.Header) {
+	for name := range header {
+		if strings.EqualFold(name, "Accept-Encoding") {
+			return
+		}
+	}
+
+	header.Set("Accept-Encoding", DEFAULT_ACCEPT_ENCODING)
+}
+
+// contentEncodings lists the encodings in a Content-Encoding header in the
+// order they were applied, identity is left out
+func contentEncodings(contentEncoding string) []string {
+	encodings := []string{}
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+func newDecompressor(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+
+	case "deflate":
+		// deflate is meant to be zlib wrapped but some servers send it raw
+		buffered := bytes.Buffer{}
+		_, err := io.Copy(&buffered, r)
+		if err != nil {
+			return nil, err
+		}
+
+		zlibReader, err := zlib.NewReader(bytes.NewReader(buffered.Bytes()))
+		if err != nil {
+			return flate.NewReader(bytes.NewReader(buffered.Bytes())), nil
+		}
+
+		return zlibReader, nil
+
+	case "br":
+		return brotli.NewReader(r), nil
+
+	case "zstd":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding: %s", encoding)
+	}
+}
+
+// Decompress undoes the encodings in a Content-Encoding header, last applied
+// first
+func Decompress(body []byte, contentEncoding string) ([]byte, error) {
+	encodings := contentEncodings(contentEncoding)
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decompressor, err := newDecompressor(encodings[i], bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		body, err = io.ReadAll(decompressor)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decompress %s body: %w", encodings[i], err)
+		}
+	}
+
+	return body, nil
+}
+
+// FormatCompressionTitle shows how much the body was compressed on the wire
+func FormatCompressionTitle(contentEncoding string, compressedSize int, decompressedSize int) string {
+	title := color.New(color.FgBlack, color.BgWhite).SprintFunc()
+
+	// tiny or already compressed bodies can come out larger than they started
+	comparison := "0% smaller"
+	switch {
+	case decompressedSize == 0 && compressedSize > 0:
+		comparison = "larger"
+	case compressedSize > decompressedSize:
+		comparison = fmt.Sprintf("%d%% larger", compressedSize*100/decompressedSize-100)
+	case decompressedSize > 0:
+		comparison = fmt.Sprintf("%d%% smaller", 100-compressedSize*100/decompressedSize)
+	}
+
+	summary := fmt.Sprintf(" %s: %s received, %s decompressed (%s) ", strings.Join(contentEncodings(contentEncoding), ", "), formatBytes(int64(compressedSize)), formatBytes(int64(decompressedSize)), comparison)
+
+	return fmt.Sprintf("%s\n", title(summary))
+}
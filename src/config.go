@@ -8,7 +8,9 @@ import (
 	"github.com/joho/godotenv"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -18,12 +20,86 @@ const (
 
 type hurlConfigFile struct {
 	EnvFilePath string `json:"env"`
+	Protoset    string `json:"protoset"`
+
+	// chroma style and terminal formatter used to highlight bodies
+	Style     string `json:"style"`
+	Formatter string `json:"formatter"`
+
+	// "auto" to page output taller than the terminal, "always" or "never"
+	Pager string `json:"pager"`
+
+	// volatile fields left out when diffing against a snapshot
+	SnapshotIgnore []string `json:"snapshotIgnore"`
 }
 
 type HurlConfig struct {
 	Version        bool
 	Verbose        bool
 	BodyOutputPath string
+	Continue       bool
+	Checksum       string
+	RemoteName     bool
+	HexDumpLength  int
+	Query          string
+	Raw            bool
+
+	// websocket sessions
+	Interactive      bool
+	WebSocketTimeout time.Duration
+
+	// gRPC requests
+	Protoset     string
+	GRPCProtocol string
+
+	// where connections are made to instead of the host in the URL
+	UnixSocket string
+	ConnectTo  map[string]string
+
+	// "pretty" for highlighted terminal output or "json" for a single JSON
+	// document that editor integrations can parse
+	OutputFormat string
+
+	// "auto", "always" or "never"
+	Color string
+
+	// response snapshots saved next to the request file
+	Snapshot        bool
+	Diff            bool
+	SnapshotHeaders []string
+	SnapshotIgnore  []string
+
+	// only print the status line and headers of responses
+	HeadersOnly bool
+
+	// "auto", "always" or "never"
+	Pager string
+
+	// leave the response body as it was received, without decompressing,
+	// decoding or prettifying it
+	RawBody bool
+
+	// log the bytes sent and received, to stderr unless there's a file
+	Trace     bool
+	TraceFile string
+
+	// skip verifying TLS certificates
+	Insecure bool
+
+	// HAR file the requests and responses are recorded to
+	HarPath string
+}
+
+// stringsFlag collects the values of a flag that can be given more than once
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
 }
 
 func getPathOfNearestConfigFile() (string, error) {
@@ -52,61 +128,265 @@ func getPathOfNearestConfigFile() (string, error) {
 	return "", nil
 }
 
+// resolveConfigPath resolves paths in hurl.json relative to the directory the
+// hurl.json file is in
+func resolveConfigPath(configFilePath string, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+
+	return filepath.Join(filepath.Dir(configFilePath), p)
+}
+
+// loadConfigFile reads the nearest hurl.json, if there is one, and loads the
+// env file it points to. Paths in it are resolved relative to it
+func loadConfigFile() (hurlConfigFile, error) {
+	// go up until hit hurl.json or hit root dir
+	configFileDir, err := getPathOfNearestConfigFile()
+	if err != nil {
+		return hurlConfigFile{}, err
+	}
+
+	var configFile hurlConfigFile
+
+	hurlJson, err := os.Open(configFileDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return configFile, nil
+	}
+	if err != nil {
+		return hurlConfigFile{}, err
+	}
+	defer hurlJson.Close()
+
+	configFileBytes, err := io.ReadAll(hurlJson)
+	if err != nil {
+		return hurlConfigFile{}, err
+	}
+
+	err = json.Unmarshal(configFileBytes, &configFile)
+	if err != nil {
+		return hurlConfigFile{}, err
+	}
+
+	if configFile.EnvFilePath != "" {
+		configFile.EnvFilePath = resolveConfigPath(configFileDir, configFile.EnvFilePath)
+
+		err = godotenv.Load(configFile.EnvFilePath)
+		if err != nil {
+			return hurlConfigFile{}, err
+		}
+	}
+
+	if configFile.Protoset != "" {
+		configFile.Protoset = resolveConfigPath(configFileDir, configFile.Protoset)
+	}
+
+	return configFile, nil
+}
+
 func InitConfig() (HurlConfig, error) {
 	version := flag.Bool("version", false, "print version")
 	verbose := flag.Bool("v", false, "verbose output")
 	bodyOutputPath := flag.String("o", "", "path to a file to output the response body")
+	continueDownload := flag.Bool("continue", false, "resume a partial download to the -o file")
+	checksum := flag.String("checksum", "", "verify the -o file against a checksum once downloaded, e.g. sha256:<hex>")
+	remoteName := flag.Bool("O", false, "output the response body to a file named after the Content-Disposition header or URL")
+	hexDumpLength := flag.Int("hex", 0, "hex dump the first N bytes of binary response bodies")
+	query := flag.String("q", "", "filter the JSON response body with a JSONPath ($.a.b) or jq (.a.b) query")
+	raw := flag.Bool("r", false, "only print the -q results, with strings unquoted")
+	interactive := flag.Bool("i", false, "send lines read from stdin over a websocket session")
+	webSocketTimeout := flag.Duration("ws-timeout", 5*time.Second, "close a websocket session after this long without a message")
+	protoset := flag.String("protoset", "", "path to a protobuf descriptor set used to encode gRPC requests")
+	grpcProtocol := flag.String("grpc-protocol", "grpc", "framing used for gRPC requests, \"grpc\" or \"connect\"")
+	unixSocket := flag.String("unix-socket", "", "connect through this unix domain socket instead of the host in the URL")
+
+	var resolves, connectTos stringsFlag
+	flag.Var(&resolves, "resolve", "connect to addr for host:port, given as host:port:addr, can be repeated")
+	flag.Var(&connectTos, "connect-to", "connect to another host:port, given as host:port:connect-host:connect-port, can be repeated")
+	outputFormat := flag.String("output-format", "pretty", "output format, \"pretty\" or \"json\"")
+	colorMode := flag.String("color", "auto", "colour output, \"auto\", \"always\" or \"never\"")
+	snapshot := flag.Bool("snapshot", false, "save the normalized response next to the request file")
+	diff := flag.Bool("diff", false, "compare the response with the snapshot saved by -snapshot")
+	snapshotHeaders := flag.String("snapshot-headers", "Content-Type", "comma separated response headers kept in snapshots")
+
+	headerOrderFlag := flag.String("header-order", "sorted", "order headers are printed in, \"sorted\" or \"wire\" for the order responses sent them in")
+	includeHeadersFlag := flag.String("include-headers", "", "comma separated header name patterns to print, e.g. Content-*,X-*")
+	excludeHeadersFlag := flag.String("exclude-headers", "", "comma separated header name patterns not to print")
+	headersOnly := flag.Bool("headers-only", false, "only print the status line and headers of the response")
+
+	rawBody := flag.Bool("raw", false, "output the response body exactly as it was received, without decompressing or formatting it")
+	insecure := flag.Bool("k", false, "don't verify TLS certificates, like curl's -k")
+	trace := flag.Bool("trace", false, "log the bytes sent and received to stderr, like curl --trace-ascii")
+	traceFile := flag.String("trace-file", "", "log the bytes sent and received to this file instead of stderr")
+	harPath := flag.String("har", "", "record the requests and responses to a HAR 1.2 file")
+	usePager := flag.Bool("pager", false, "show the output through $PAGER even if it fits on the screen")
+	noPager := flag.Bool("no-pager", false, "never show the output through $PAGER")
+
+	var snapshotIgnore stringsFlag
+	flag.Var(&snapshotIgnore, "ignore", "path left out of -diff, e.g. body.items[*].updated_at, can be repeated")
 
 	flag.Parse()
 
-	// go up until hit hurl.json or hit root dir
-	configFileDir, err := getPathOfNearestConfigFile()
+	hurlConfigFile, err := loadConfigFile()
 	if err != nil {
 		return HurlConfig{}, err
 	}
 
-	hurlJson, err := os.Open(configFileDir)
+	err = SetHighlightStyle(hurlConfigFile.Style, hurlConfigFile.Formatter)
+	if err != nil {
+		return HurlConfig{}, err
+	}
 
-	if !errors.Is(err, os.ErrNotExist) {
-		if err != nil {
-			return HurlConfig{}, err
-		}
+	err = SetHeaderDisplay(*headerOrderFlag, splitHeaderPatterns(*includeHeadersFlag), splitHeaderPatterns(*excludeHeadersFlag))
+	if err != nil {
+		return HurlConfig{}, err
+	}
 
-		configFileBytes, err := io.ReadAll(hurlJson)
-		if err != nil {
-			return HurlConfig{}, err
-		}
+	pagerMode := hurlConfigFile.Pager
+	if pagerMode == "" {
+		pagerMode = "auto"
+	}
+	if pagerMode != "auto" && pagerMode != "always" && pagerMode != "never" {
+		return HurlConfig{}, fmt.Errorf("pager in hurl.json must be \"auto\", \"always\" or \"never\", got: %s", pagerMode)
+	}
 
-		var hurlConfigFile hurlConfigFile
-		err = json.Unmarshal(configFileBytes, &hurlConfigFile)
-		if err != nil {
-			return HurlConfig{}, err
-		}
+	if *usePager && *noPager {
+		return HurlConfig{}, errors.New("-pager and -no-pager can't be used together")
+	}
+	if *usePager {
+		pagerMode = "always"
+	}
+	if *noPager {
+		pagerMode = "never"
+	}
 
-		// relative paths
-		envFilePath := hurlConfigFile.EnvFilePath
-		if !strings.HasPrefix(hurlConfigFile.EnvFilePath, "/") {
-			if strings.HasPrefix(hurlConfigFile.EnvFilePath, "./") {
-				envFilePath := strings.Replace(hurlConfigFile.EnvFilePath, "./", "", 1)
-				hurlConfigFile.EnvFilePath = envFilePath
-			}
+	if *protoset == "" {
+		*protoset = hurlConfigFile.Protoset
+	}
 
-			configFileDirPath := strings.Split(configFileDir, "/")
-			configFileDirPath = configFileDirPath[:len(configFileDirPath)-1]
-			configFileDir = strings.Join(configFileDirPath, "/")
+	if *grpcProtocol != "grpc" && *grpcProtocol != "connect" {
+		return HurlConfig{}, fmt.Errorf("-grpc-protocol must be \"grpc\" or \"connect\", got: %s", *grpcProtocol)
+	}
+
+	if *continueDownload && *bodyOutputPath == "" {
+		return HurlConfig{}, errors.New("-continue requires -o")
+	}
+
+	if *remoteName && *bodyOutputPath != "" {
+		return HurlConfig{}, errors.New("-O and -o can't be used together")
+	}
+
+	if *query != "" && (*bodyOutputPath != "" || *remoteName) {
+		return HurlConfig{}, errors.New("-q can't be used with -o or -O")
+	}
+
+	if *raw && *query == "" {
+		return HurlConfig{}, errors.New("-r requires -q")
+	}
+
+	if *checksum != "" && *bodyOutputPath == "" && !*remoteName {
+		return HurlConfig{}, errors.New("-checksum requires -o or -O")
+	}
+
+	if *colorMode != "auto" && *colorMode != "always" && *colorMode != "never" {
+		return HurlConfig{}, fmt.Errorf("-color must be \"auto\", \"always\" or \"never\", got: %s", *colorMode)
+	}
+
+	if *outputFormat != "pretty" && *outputFormat != "json" {
+		return HurlConfig{}, fmt.Errorf("-output-format must be \"pretty\" or \"json\", got: %s", *outputFormat)
+	}
 
-			envFilePath = fmt.Sprintf("/%s/%s", configFileDir, hurlConfigFile.EnvFilePath)
+	if *outputFormat == "json" && *continueDownload {
+		return HurlConfig{}, errors.New("-continue is not supported with -output-format json")
+	}
+
+	if *outputFormat == "json" && *query != "" {
+		return HurlConfig{}, errors.New("-q is not supported with -output-format json")
+	}
+
+	if *snapshot && *diff {
+		return HurlConfig{}, errors.New("-snapshot and -diff can't be used together")
+	}
+
+	if (*snapshot || *diff) && *outputFormat == "json" {
+		return HurlConfig{}, errors.New("-snapshot and -diff are not supported with -output-format json")
+	}
+
+	if (*snapshot || *diff) && (*query != "" || *continueDownload) {
+		return HurlConfig{}, errors.New("-snapshot and -diff can't be used with -q or -continue")
+	}
+
+	if *rawBody && (*query != "" || *snapshot || *diff || *continueDownload) {
+		return HurlConfig{}, errors.New("-raw can't be used with -q, -snapshot, -diff or -continue")
+	}
+
+	if *headersOnly && (*bodyOutputPath != "" || *remoteName || *query != "" || *snapshot || *diff) {
+		return HurlConfig{}, errors.New("-headers-only can't be used with -o, -O, -q, -snapshot or -diff")
+	}
+
+	headerNames := []string{}
+	for _, name := range strings.Split(*snapshotHeaders, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			headerNames = append(headerNames, name)
 		}
+	}
 
-		err = godotenv.Load(envFilePath)
+	connectTo := make(map[string]string)
+	for _, resolve := range resolves {
+		hostPort, target, err := parseResolve(resolve)
 		if err != nil {
 			return HurlConfig{}, err
 		}
+		connectTo[hostPort] = target
+	}
+	for _, c := range connectTos {
+		hostPort, target, err := parseConnectTo(c)
+		if err != nil {
+			return HurlConfig{}, err
+		}
+		connectTo[hostPort] = target
 	}
 
 	return HurlConfig{
-		*version,
-		*verbose,
-		*bodyOutputPath,
+		Version:        *version,
+		Verbose:        *verbose,
+		BodyOutputPath: *bodyOutputPath,
+		Continue:       *continueDownload,
+		Checksum:       *checksum,
+		RemoteName:     *remoteName,
+		HexDumpLength:  *hexDumpLength,
+		Query:          *query,
+		Raw:            *raw,
+
+		Interactive:      *interactive,
+		WebSocketTimeout: *webSocketTimeout,
+
+		Protoset:     *protoset,
+		GRPCProtocol: *grpcProtocol,
+
+		UnixSocket: *unixSocket,
+		ConnectTo:  connectTo,
+
+		OutputFormat: *outputFormat,
+		Color:        *colorMode,
+
+		Snapshot:        *snapshot,
+		Diff:            *diff,
+		SnapshotHeaders: headerNames,
+		SnapshotIgnore:  append(hurlConfigFile.SnapshotIgnore, snapshotIgnore...),
+
+		HeadersOnly: *headersOnly,
+
+		Pager: pagerMode,
+
+		RawBody: *rawBody,
+
+		Trace:     *trace || *traceFile != "",
+		TraceFile: *traceFile,
+
+		Insecure: *insecure,
+
+		HarPath: *harPath,
 	}, nil
 }
@@ -0,0 +1,229 @@
+package src
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resumeState is kept next to a partial download so a later run knows whether
+// the bytes on disk still belong to the same version of the resource
+type resumeState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func resumeStatePath(bodyOutputPath string) string {
+	return bodyOutputPath + ".hurl-resume"
+}
+
+func readResumeState(bodyOutputPath string) (resumeState, error) {
+	stateBytes, err := os.ReadFile(resumeStatePath(bodyOutputPath))
+	if err != nil {
+		return resumeState{}, err
+	}
+
+	var state resumeState
+	err = json.Unmarshal(stateBytes, &state)
+	if err != nil {
+		return resumeState{}, err
+	}
+
+	return state, nil
+}
+
+func writeResumeState(bodyOutputPath string, state resumeState) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(resumeStatePath(bodyOutputPath), stateBytes, 0644)
+}
+
+// PrepareResume adds Range and If-Range headers to req when a partial download
+// exists at bodyOutputPath, returns the offset the download is resumed from
+func PrepareResume(req *http.Request, bodyOutputPath string) (int64, error) {
+	// ranges are of the encoded body, the file is asked for as is so the
+	// parts on disk can simply be appended to
+	req.Header.Set("Accept-Encoding", "identity")
+
+	file, err := os.Stat(bodyOutputPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if file.Size() == 0 {
+		return 0, nil
+	}
+
+	state, err := readResumeState(bodyOutputPath)
+	if errors.Is(err, os.ErrNotExist) {
+		PrintWarning(fmt.Errorf("no resume information for %s, downloading from the start", bodyOutputPath))
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if state.URL != req.URL.String() {
+		PrintWarning(fmt.Errorf("%s was downloaded from a different URL, downloading from the start", bodyOutputPath))
+		return 0, nil
+	}
+
+	// If-Range makes the server send the whole resource if it changed
+	// since the partial download, rather than a range of the new one
+	validator := state.ETag
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = state.LastModified
+	}
+	if validator == "" {
+		PrintWarning(fmt.Errorf("server gave no ETag or Last-Modified for %s, downloading from the start", bodyOutputPath))
+		return 0, nil
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", file.Size()))
+	req.Header.Set("If-Range", validator)
+
+	return file.Size(), nil
+}
+
+func parseContentRangeStart(contentRange string) (int64, error) {
+	// Content-Range: bytes <start>-<end>/<size>
+	unit, rangeSpec, found := strings.Cut(contentRange, " ")
+	if !found || unit != "bytes" {
+		return 0, fmt.Errorf("unsupported Content-Range: %s", contentRange)
+	}
+
+	start, _, found := strings.Cut(rangeSpec, "-")
+	if !found {
+		return 0, fmt.Errorf("malformed Content-Range: %s", contentRange)
+	}
+
+	return strconv.ParseInt(start, 10, 64)
+}
+
+// requestedURL is the URL asked for before any redirects, which is what
+// PrepareResume compares against since the URL redirected to can change
+// between runs
+func requestedURL(res http.Response) *url.URL {
+	req := res.Request
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+
+	return req.URL
+}
+
+// WriteResumableBody streams the response body to bodyOutputPath, appending
+// to the partial file on a 206 and starting over on anything else
+func WriteResumableBody(res http.Response, bodyOutputPath string, offset int64) error {
+	if offset > 0 && res.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		PrintWarning(fmt.Errorf("server could not satisfy range, %s is likely already complete", bodyOutputPath))
+		return os.Remove(resumeStatePath(bodyOutputPath))
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("server responded with %s, %s left untouched", res.Status, bodyOutputPath)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if res.StatusCode == http.StatusPartialContent && offset > 0 {
+		start, err := parseContentRangeStart(res.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+
+		if start != offset {
+			return fmt.Errorf("server resumed from byte %d but %s has %d bytes", start, bodyOutputPath, offset)
+		}
+
+		flags = os.O_WRONLY | os.O_APPEND
+	} else if offset > 0 {
+		PrintWarning(errors.New("server did not resume the download, downloading from the start"))
+	}
+
+	err := writeResumeState(bodyOutputPath, resumeState{
+		URL:          requestedURL(res).String(),
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(bodyOutputPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, res.Body)
+	if err != nil {
+		return fmt.Errorf("download interrupted, rerun with -continue to resume: %w", err)
+	}
+
+	err = os.Remove(resumeStatePath(bodyOutputPath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// VerifyChecksum checks the file at path against a checksum given as
+// <algorithm>:<hex digest>
+func VerifyChecksum(path string, checksum string) error {
+	algorithm, expected, found := strings.Cut(checksum, ":")
+	if !found {
+		return fmt.Errorf("checksum must be in the form <algorithm>:<hex>, got: %s", checksum)
+	}
+
+	var h hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+	}
+
+	return nil
+}
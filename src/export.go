@@ -0,0 +1,368 @@
+package src
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+var EXPORT_TARGETS = []string{"curl", "httpie", "go", "python-requests", "js-fetch"}
+
+// templatePart is literal text or, when Variable is set, a reference to an
+// environment variable
+type templatePart struct {
+	Text     string
+	Variable string
+}
+
+// templateString is a URL or header value with the variables it uses kept as
+// references to be read from the environment when the snippet runs
+type templateString []templatePart
+
+func (t templateString) HasVariables() bool {
+	for _, part := range t {
+		if part.Variable != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t templateString) IsEmpty() bool {
+	for _, part := range t {
+		if part.Text != "" || part.Variable != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// render writes the string as an expression in the target, literal text is
+// quoted and the parts are joined with concat
+func (t templateString) render(quote func(string) string, variable func(string) string, concat string) string {
+	if len(t) == 0 {
+		return quote("")
+	}
+
+	parts := []string{}
+	for _, part := range t {
+		if part.Variable != "" {
+			parts = append(parts, variable(part.Variable))
+		} else {
+			parts = append(parts, quote(part.Text))
+		}
+	}
+
+	return strings.Join(parts, concat)
+}
+
+// parseTemplateString splits text into literal text and the {{ }} in it
+func parseTemplateString(text string) templateString {
+	t := templateString{}
+
+	offset := 0
+	for _, variable := range FindTemplateVariables(text, Pos{1, 0}) {
+		if variable.Pos.Col > offset {
+			t = append(t, templatePart{Text: text[offset:variable.Pos.Col]})
+		}
+		t = append(t, templatePart{Variable: variable.Name})
+		offset = variable.End.Col
+	}
+
+	if offset < len(text) {
+		t = append(t, templatePart{Text: text[offset:]})
+	}
+
+	return t
+}
+
+type exportedHeader struct {
+	Name  string
+	Value templateString
+}
+
+// exportedRequest is the request a request file makes, the way it's written
+// out as a snippet for another tool
+type exportedRequest struct {
+	Method    string
+	URL       templateString
+	Headers   []exportedHeader
+	Body      string
+	FileEmbed string
+	Multipart []MultiPartItem
+}
+
+func (r *exportedRequest) IsMultipart() bool {
+	return len(r.Multipart) > 0
+}
+
+// NewExportedRequest reads the request a request file makes, with
+// keepVariables the variables in the URL and headers are kept as references
+// instead of their values
+func NewExportedRequest(src []byte, keepVariables bool) (*exportedRequest, error) {
+	ast, err := ParseHurlAST(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// the variables are meant to be missing when they're kept, other
+	// warnings are still printed
+	CollectWarnings = keepVariables
+	hurlFile, err := ParseHurlFile(bytes.NewReader(src))
+	CollectWarnings = false
+	for _, warning := range Warnings {
+		if !strings.HasPrefix(warning, "could not find environment variable") {
+			PrintWarning(errors.New(warning))
+		}
+	}
+	Warnings = nil
+	if err != nil {
+		return nil, err
+	}
+
+	if hurlFile.IsWebSocket() {
+		return nil, errors.New("WebSocket requests can't be exported")
+	}
+	if hurlFile.IsGRPC() {
+		return nil, errors.New("gRPC requests can't be exported")
+	}
+
+	req := &exportedRequest{
+		Method:    hurlFile.Method,
+		URL:       templateString{{Text: hurlFile.URL.String()}},
+		Body:      string(hurlFile.Body),
+		FileEmbed: hurlFile.FileEmbed,
+		Multipart: hurlFile.MultipartFormData,
+	}
+
+	// GraphQL GET requests have their query added to the URL so it's no
+	// longer the URL as written
+	if keepVariables && !(hurlFile.IsGraphQL && hurlFile.Method == "GET") {
+		req.URL = parseTemplateString(ast.RequestLine.URL)
+	}
+
+	// headers are written in the order of the file, only the last of a
+	// repeated header is sent
+	seen := map[string]void{}
+	for i := len(ast.Headers) - 1; i >= 0; i-- {
+		name, err := interpolateEnvVar([]byte(ast.Headers[i].Name))
+		if err != nil {
+			return nil, err
+		}
+		name = http.CanonicalHeaderKey(name)
+
+		value, exists := hurlFile.Headers[hurlFile.headerName(name)]
+		if _, ok := seen[name]; ok || !exists || name == "User-Agent" {
+			continue
+		}
+		seen[name] = member
+
+		header := exportedHeader{name, templateString{{Text: value}}}
+		if keepVariables && strings.Contains(ast.Headers[i].Value, "{{") {
+			header.Value = parseTemplateString(ast.Headers[i].Value)
+		}
+
+		req.Headers = append([]exportedHeader{header}, req.Headers...)
+	}
+
+	// headers hurl adds itself, like a Content-Type for bodies without one
+	added := []string{}
+	for name := range hurlFile.Headers {
+		if _, ok := seen[http.CanonicalHeaderKey(name)]; !ok && !strings.EqualFold(name, "User-Agent") {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		req.Headers = append(req.Headers, exportedHeader{http.CanonicalHeaderKey(name), templateString{{Text: hurlFile.Headers[name]}}})
+	}
+
+	// the boundary is chosen by the tool sending the request
+	if req.IsMultipart() {
+		headers := []exportedHeader{}
+		for _, header := range req.Headers {
+			if header.Name != "Content-Type" {
+				headers = append(headers, header)
+			}
+		}
+		req.Headers = headers
+	}
+
+	return req, nil
+}
+
+// ExportRequest writes the request as a snippet for the target
+func ExportRequest(req *exportedRequest, target string) (string, error) {
+	switch target {
+	case "curl":
+		return exportCurl(req), nil
+	case "httpie":
+		return exportHTTPie(req), nil
+	case "go":
+		return exportGo(req)
+	case "python-requests":
+		return exportPythonRequests(req), nil
+	case "js-fetch":
+		return exportJSFetch(req), nil
+	default:
+		return "", fmt.Errorf("unknown target %q, expected one of %s", target, strings.Join(EXPORT_TARGETS, ", "))
+	}
+}
+
+// shellQuote quotes literal text for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellWord writes a template string as a single shell word, double quoted
+// when it has variables so they're expanded
+func shellWord(t templateString) string {
+	if !t.HasVariables() {
+		return shellQuote(t.render(func(s string) string { return s }, nil, ""))
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	word := t.render(escaper.Replace, func(name string) string { return "${" + name + "}" }, "")
+
+	return `"` + word + `"`
+}
+
+// headerShellWord writes a header as a shell word, empty headers are written
+// as "Name;" like curl and HTTPie expect
+func headerShellWord(header exportedHeader, separator string) string {
+	if header.Value.IsEmpty() {
+		return shellQuote(header.Name + ";")
+	}
+
+	value := append(templateString{{Text: header.Name + separator}}, header.Value...)
+	return shellWord(value)
+}
+
+func exportCurl(req *exportedRequest) string {
+	args := []string{"curl"}
+
+	hasBody := req.Body != "" || req.FileEmbed != "" || req.IsMultipart()
+	if req.Method != "GET" && !(req.Method == "POST" && hasBody) {
+		args = append(args, "-X "+req.Method)
+	}
+
+	args = append(args, shellWord(req.URL))
+	args = []string{strings.Join(args, " ")}
+
+	for _, header := range req.Headers {
+		args = append(args, "-H "+headerShellWord(header, ": "))
+	}
+
+	switch {
+	case req.IsMultipart():
+		for _, item := range req.Multipart {
+			if item.IsFilePath {
+				args = append(args, "-F "+shellQuote(item.Name+"=@"+item.Value))
+			} else {
+				// --form-string doesn't treat a leading @ or < as a file
+				args = append(args, "--form-string "+shellQuote(item.Name+"="+item.Value))
+			}
+		}
+
+	case req.FileEmbed != "":
+		args = append(args, "--data-binary "+shellQuote("@"+req.FileEmbed))
+
+	case req.Body != "":
+		args = append(args, "--data-raw "+shellQuote(req.Body))
+	}
+
+	return strings.Join(args, " \\\n  ") + "\n"
+}
+
+func exportHTTPie(req *exportedRequest) string {
+	args := []string{"http"}
+
+	if req.IsMultipart() {
+		args = append(args, "--multipart")
+	}
+
+	args = append(args, req.Method, shellWord(req.URL))
+	args = []string{strings.Join(args, " ")}
+
+	for _, header := range req.Headers {
+		args = append(args, headerShellWord(header, ":"))
+	}
+
+	switch {
+	case req.IsMultipart():
+		for _, item := range req.Multipart {
+			if item.IsFilePath {
+				args = append(args, shellQuote(item.Name+"@"+item.Value))
+			} else {
+				args = append(args, shellQuote(item.Name+"="+item.Value))
+			}
+		}
+
+	case req.FileEmbed != "":
+		// the body is read from stdin
+		args[len(args)-1] += " \\\n  < " + shellQuote(req.FileEmbed)
+
+	case req.Body != "":
+		args = append(args, "--raw "+shellQuote(req.Body))
+	}
+
+	return strings.Join(args, " \\\n  ") + "\n"
+}
+
+// RunExport is the `hurl export` command, it writes the request a request
+// file makes as a snippet for people without hurl
+func RunExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl export -to target [-keep-vars] file")
+		flags.PrintDefaults()
+	}
+	target := flags.String("to", "curl", fmt.Sprintf("what to export the request as, one of %s", strings.Join(EXPORT_TARGETS, ", ")))
+	keepVariables := flags.Bool("keep-vars", false, "read variables in the URL and headers from the environment when the snippet runs instead of using their values")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	_, err = loadConfigFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl export: %s\n", err.Error())
+		return 1
+	}
+
+	path := flags.Arg(0)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl export: %s\n", err.Error())
+		return 1
+	}
+
+	req, err := NewExportedRequest(src, *keepVariables)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl export: %s\n", fileError(path, err))
+		return 1
+	}
+
+	snippet, err := ExportRequest(req, *target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl export: %s\n", err.Error())
+		return 1
+	}
+
+	fmt.Print(snippet)
+
+	return 0
+}
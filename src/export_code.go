@@ -0,0 +1,227 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// jsonQuote quotes a string as a JSON string, which is also a valid Python and
+// JavaScript string literal
+func jsonQuote(s string) string {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// goBodyLiteral is a raw string literal when the body can be written as one so
+// JSON bodies stay readable
+func goBodyLiteral(body string) string {
+	if !strings.ContainsAny(body, "`\r") {
+		return "`" + body + "`"
+	}
+
+	return strconv.Quote(body)
+}
+
+func exportGo(req *exportedRequest) (string, error) {
+	goString := func(t templateString) string {
+		return t.render(strconv.Quote, func(name string) string { return fmt.Sprintf("os.Getenv(%q)", name) }, " + ")
+	}
+
+	code := strings.Builder{}
+	code.WriteString("package main\n\n")
+	code.WriteString("import (\n\"fmt\"\n\"io\"\n\"log\"\n\"net/http\"\n\"os\"\n")
+	switch {
+	case req.IsMultipart():
+		code.WriteString("\"bytes\"\n\"mime/multipart\"\n")
+	case req.Body != "":
+		code.WriteString("\"strings\"\n")
+	}
+	code.WriteString(")\n\n")
+
+	code.WriteString("func main() {\n")
+
+	body := "nil"
+	switch {
+	case req.IsMultipart():
+		body = "body"
+		code.WriteString("body := &bytes.Buffer{}\nwriter := multipart.NewWriter(body)\n")
+		for _, item := range req.Multipart {
+			if item.IsFilePath {
+				fmt.Fprintf(&code, "if err := addFile(writer, %q, %q); err != nil {\nlog.Fatal(err)\n}\n", item.Name, item.Value)
+			} else {
+				fmt.Fprintf(&code, "if err := writer.WriteField(%q, %s); err != nil {\nlog.Fatal(err)\n}\n", item.Name, strconv.Quote(item.Value))
+			}
+		}
+		code.WriteString("if err := writer.Close(); err != nil {\nlog.Fatal(err)\n}\n\n")
+
+	case req.FileEmbed != "":
+		body = "body"
+		fmt.Fprintf(&code, "body, err := os.Open(%q)\nif err != nil {\nlog.Fatal(err)\n}\ndefer body.Close()\n\n", req.FileEmbed)
+
+	case req.Body != "":
+		body = "body"
+		fmt.Fprintf(&code, "body := strings.NewReader(%s)\n\n", goBodyLiteral(req.Body))
+	}
+
+	fmt.Fprintf(&code, "req, err := http.NewRequest(%q, %s, %s)\nif err != nil {\nlog.Fatal(err)\n}\n", req.Method, goString(req.URL), body)
+	for _, header := range req.Headers {
+		fmt.Fprintf(&code, "req.Header.Set(%q, %s)\n", header.Name, goString(header.Value))
+	}
+	if req.IsMultipart() {
+		code.WriteString("req.Header.Set(\"Content-Type\", writer.FormDataContentType())\n")
+	}
+
+	code.WriteString("\nres, err := http.DefaultClient.Do(req)\nif err != nil {\nlog.Fatal(err)\n}\ndefer res.Body.Close()\n\n")
+	code.WriteString("fmt.Println(res.Status)\nio.Copy(os.Stdout, res.Body)\n}\n")
+
+	if req.IsMultipart() {
+		code.WriteString("\nfunc addFile(writer *multipart.Writer, name string, path string) error {\n")
+		code.WriteString("file, err := os.Open(path)\nif err != nil {\nreturn err\n}\ndefer file.Close()\n\n")
+		code.WriteString("part, err := writer.CreateFormFile(name, filepath.Base(path))\nif err != nil {\nreturn err\n}\n\n")
+		code.WriteString("_, err = io.Copy(part, file)\nreturn err\n}\n")
+		// added here since only addFile uses it
+		return formatGo(strings.Replace(code.String(), "\"os\"\n", "\"os\"\n\"path/filepath\"\n", 1))
+	}
+
+	return formatGo(code.String())
+}
+
+// formatGo runs gofmt on the snippet, which also sorts its imports
+func formatGo(code string) (string, error) {
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return "", err
+	}
+
+	return string(formatted), nil
+}
+
+// pythonBodyLiteral is a triple quoted string when the body can be written as
+// one so JSON bodies stay readable
+func pythonBodyLiteral(body string) string {
+	if !strings.ContainsAny(body, "\\\r") && !strings.Contains(body, `"""`) && !strings.HasSuffix(body, `"`) {
+		return `"""` + body + `"""`
+	}
+
+	return jsonQuote(body)
+}
+
+func exportPythonRequests(req *exportedRequest) string {
+	pythonString := func(t templateString) string {
+		return t.render(jsonQuote, func(name string) string { return fmt.Sprintf("os.environ[%s]", jsonQuote(name)) }, " + ")
+	}
+
+	usesEnvironment := req.URL.HasVariables()
+	for _, header := range req.Headers {
+		usesEnvironment = usesEnvironment || header.Value.HasVariables()
+	}
+
+	code := strings.Builder{}
+	if usesEnvironment {
+		code.WriteString("import os\n\n")
+	}
+	code.WriteString("import requests\n\n")
+
+	arguments := []string{}
+	switch {
+	case req.IsMultipart():
+		// requests sends fields with a filename of None as plain values
+		files := []string{}
+		for _, item := range req.Multipart {
+			if item.IsFilePath {
+				files = append(files, fmt.Sprintf("(%s, (%s, open(%s, \"rb\")))", jsonQuote(item.Name), jsonQuote(filepath.Base(item.Value)), jsonQuote(item.Value)))
+			} else {
+				files = append(files, fmt.Sprintf("(%s, (None, %s))", jsonQuote(item.Name), jsonQuote(item.Value)))
+			}
+		}
+		arguments = append(arguments, "files=[\n        "+strings.Join(files, ",\n        ")+",\n    ]")
+
+	case req.FileEmbed != "":
+		arguments = append(arguments, fmt.Sprintf("data=open(%s, \"rb\")", jsonQuote(req.FileEmbed)))
+
+	case req.Body != "":
+		// encoded so it's sent as UTF-8 rather than Latin-1
+		fmt.Fprintf(&code, "body = %s\n\n", pythonBodyLiteral(req.Body))
+		arguments = append(arguments, "data=body.encode()")
+	}
+
+	if len(req.Headers) > 0 {
+		headers := []string{}
+		for _, header := range req.Headers {
+			headers = append(headers, fmt.Sprintf("%s: %s", jsonQuote(header.Name), pythonString(header.Value)))
+		}
+		arguments = append([]string{"headers={\n        " + strings.Join(headers, ",\n        ") + ",\n    }"}, arguments...)
+	}
+
+	arguments = append([]string{jsonQuote(req.Method), pythonString(req.URL)}, arguments...)
+
+	code.WriteString("response = requests.request(\n    " + strings.Join(arguments, ",\n    ") + ",\n)\n\n")
+	code.WriteString("print(response.status_code, response.reason)\nprint(response.text)\n")
+
+	return code.String()
+}
+
+// jsBodyLiteral is a template literal when the body can be written as one so
+// JSON bodies stay readable
+func jsBodyLiteral(body string) string {
+	if !strings.ContainsAny(body, "`\\\r") && !strings.Contains(body, "${") {
+		return "`" + body + "`"
+	}
+
+	return jsonQuote(body)
+}
+
+func exportJSFetch(req *exportedRequest) string {
+	jsString := func(t templateString) string {
+		return t.render(jsonQuote, func(name string) string { return "process.env." + name }, " + ")
+	}
+
+	code := strings.Builder{}
+	if req.IsMultipart() || req.FileEmbed != "" {
+		code.WriteString("import { readFileSync } from \"node:fs\";\n\n")
+	}
+
+	options := []string{fmt.Sprintf("method: %s", jsonQuote(req.Method))}
+
+	if len(req.Headers) > 0 {
+		headers := []string{}
+		for _, header := range req.Headers {
+			headers = append(headers, fmt.Sprintf("%s: %s", jsonQuote(header.Name), jsString(header.Value)))
+		}
+		options = append(options, "headers: {\n    "+strings.Join(headers, ",\n    ")+",\n  }")
+	}
+
+	switch {
+	case req.IsMultipart():
+		code.WriteString("const form = new FormData();\n")
+		for _, item := range req.Multipart {
+			if item.IsFilePath {
+				fmt.Fprintf(&code, "form.append(%s, new Blob([readFileSync(%s)]), %s);\n", jsonQuote(item.Name), jsonQuote(item.Value), jsonQuote(filepath.Base(item.Value)))
+			} else {
+				fmt.Fprintf(&code, "form.append(%s, %s);\n", jsonQuote(item.Name), jsonQuote(item.Value))
+			}
+		}
+		code.WriteString("\n")
+		options = append(options, "body: form")
+
+	case req.FileEmbed != "":
+		options = append(options, fmt.Sprintf("body: readFileSync(%s)", jsonQuote(req.FileEmbed)))
+
+	case req.Body != "":
+		options = append(options, "body: "+jsBodyLiteral(req.Body))
+	}
+
+	fmt.Fprintf(&code, "const response = await fetch(%s, {\n  %s,\n});\n\n", jsString(req.URL), strings.Join(options, ",\n  "))
+	code.WriteString("console.log(response.status, response.statusText);\nconsole.log(await response.text());\n")
+
+	return code.String()
+}
@@ -0,0 +1,465 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// FormatHurlFile rewrites a request file canonically: one space in the
+// request line, canonical header names followed by `: `, a single blank line
+// before the body, JSON bodies indented with 2 spaces, form bodies with
+// canonical percent encoding and multipart lines aligned. Formatting never
+// changes the request the file describes
+func FormatHurlFile(src []byte) ([]byte, error) {
+	ast, err := ParseHurlAST(src)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := printHurlAST(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	// a mistake here would silently change requests, so check the request
+	// is the same and that formatting again doesn't change anything
+	formattedAst, err := ParseHurlAST(formatted)
+	if err != nil {
+		return nil, fmt.Errorf("formatted file doesn't parse: %w", err)
+	}
+
+	if requestMeaning(ast) != requestMeaning(formattedAst) {
+		return nil, errors.New("formatting would change the request, the file was left alone")
+	}
+
+	return formatted, nil
+}
+
+func printHurlAST(ast *HurlAST) ([]byte, error) {
+	buffer := bytes.Buffer{}
+
+	buffer.WriteString(fmt.Sprintf("%s %s\n", ast.RequestLine.Method, ast.RequestLine.URL))
+
+	for _, header := range ast.Headers {
+		buffer.WriteString(fmt.Sprintf("%s: %s\n", http.CanonicalHeaderKey(header.Name), header.Value))
+	}
+
+	body, err := formatBodyLines(ast)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > 0 {
+		buffer.WriteString("\n")
+		buffer.WriteString(strings.Join(body, "\n"))
+		buffer.WriteString("\n")
+	} else if ast.HasBody {
+		// a blank line after the headers still starts a body, which gets a
+		// Content-Type when there isn't one, so it's kept
+		buffer.WriteString("\n")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// trimBlankLines drops the blank lines at the start and end of a body
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func bodyLineTexts(ast *HurlAST) []string {
+	lines := []string{}
+	for _, line := range ast.Body {
+		lines = append(lines, line.Text)
+	}
+
+	return lines
+}
+
+// formatBodyLines formats the body based on what kind of body the request
+// file has, the same way ParseHurlFile decides how to read it
+func formatBodyLines(ast *HurlAST) ([]string, error) {
+	lines := bodyLineTexts(ast)
+
+	if len(trimBlankLines(lines)) == 0 {
+		return []string{}, nil
+	}
+
+	if ast.IsWebSocket() {
+		return formatWebSocketLines(lines), nil
+	}
+
+	if ast.IsGraphQL() {
+		return formatGraphQLLines(lines)
+	}
+
+	if hasFileEmbed(lines) {
+		return lines, nil
+	}
+
+	mediaType := ast.MediaType()
+
+	switch {
+	case ast.RequestLine.Method == "GRPC" || lexerForMediaType(mediaType) == "json":
+		return formatJsonLines(lines), nil
+
+	case ast.IsMultipart():
+		return formatMultiPartLines(ast)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		return formatFormLines(lines), nil
+
+	default:
+		return lines, nil
+	}
+}
+
+func hasFileEmbed(lines []string) bool {
+	for _, line := range lines {
+		if extractFileEmbedPath(line) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatJsonLines indents valid JSON, anything else like bodies using
+// template variables is left as it is
+func formatJsonLines(lines []string) []string {
+	body := []byte(strings.Join(lines, "\n"))
+	if !json.Valid(body) {
+		return lines
+	}
+
+	prettified, err := PrettifyJson(bytes.TrimSpace(body))
+	if err != nil {
+		return lines
+	}
+
+	return strings.Split(string(prettified), "\n")
+}
+
+// formatWebSocketLines separates messages with exactly one blank line
+func formatWebSocketLines(lines []string) []string {
+	formatted := []string{}
+	for _, line := range trimBlankLines(lines) {
+		if isBlank(line) {
+			if formatted[len(formatted)-1] != "" {
+				formatted = append(formatted, "")
+			}
+			continue
+		}
+
+		formatted = append(formatted, line)
+	}
+
+	return formatted
+}
+
+// formatMultiPartLines aligns the values of multipart lines into a column,
+// every line has to be a multipart line, blank ones included, as that's how
+// ParseHurlFile reads them
+func formatMultiPartLines(ast *HurlAST) ([]string, error) {
+	items := []MultiPartItem{}
+	names := []string{}
+	nameWidth := 0
+
+	for _, line := range ast.Body {
+		item, err := parseMultiPartLine(line.Text)
+		if err != nil {
+			return nil, &SyntaxError{Pos{line.Pos.Line, leadingSpace(line.Text) + 1}, err.Error()}
+		}
+
+		name := fmt.Sprintf("name=\"%s\";", item.Name)
+		nameWidth = max(nameWidth, len(name))
+
+		items = append(items, item)
+		names = append(names, name)
+	}
+
+	formatted := []string{}
+	for i, item := range items {
+		valueKey := "value"
+		if item.IsFilePath {
+			valueKey = "filename"
+		}
+
+		formatted = append(formatted, fmt.Sprintf("form-data; %-*s %s=\"%s\"", nameWidth, names[i], valueKey, item.Value))
+	}
+
+	return formatted, nil
+}
+
+// formatGraphQLLines keeps the query as it was written and indents the
+// variables
+func formatGraphQLLines(lines []string) ([]string, error) {
+	sc := bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))
+	graphQLRequest, err := parseGraphQL(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := append([]string{GRAPHQL_BODY_TAG}, strings.Split(graphQLRequest.Query, "\n")...)
+
+	if len(graphQLRequest.Variables) > 0 {
+		variables, err := PrettifyJson(graphQLRequest.Variables)
+		if err != nil {
+			return nil, err
+		}
+
+		formatted = append(formatted, "", GRAPHQL_VARIABLES_TAG)
+		formatted = append(formatted, strings.Split(string(variables), "\n")...)
+	}
+
+	if graphQLRequest.OperationName != "" {
+		formatted = append(formatted, "", fmt.Sprintf("%s=%s", GRAPHQL_OPERATION_NAME_TAG, graphQLRequest.OperationName))
+	}
+
+	return formatted, nil
+}
+
+// formatFormLines re-encodes the pairs of a single line form body so the same
+// characters are always escaped the same way
+func formatFormLines(lines []string) []string {
+	if len(lines) != 1 || strings.Contains(lines[0], "{{") {
+		return lines
+	}
+
+	pairs, err := parseFormPairs(lines[0])
+	if err != nil {
+		return lines
+	}
+
+	encoded := []string{}
+	for _, pair := range pairs {
+		if !pair.HasValue {
+			encoded = append(encoded, url.QueryEscape(pair.Key))
+			continue
+		}
+
+		encoded = append(encoded, fmt.Sprintf("%s=%s", url.QueryEscape(pair.Key), url.QueryEscape(pair.Value)))
+	}
+
+	return []string{strings.Join(encoded, "&")}
+}
+
+type formPair struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// parseFormPairs decodes every pair of a form body in order
+func parseFormPairs(form string) ([]formPair, error) {
+	pairs := []formPair{}
+	for _, pair := range strings.Split(form, "&") {
+		key, value, hasValue := strings.Cut(pair, "=")
+
+		decodedKey, err := url.QueryUnescape(key)
+		if err != nil {
+			return nil, err
+		}
+
+		decodedValue, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, formPair{decodedKey, decodedValue, hasValue})
+	}
+
+	return pairs, nil
+}
+
+// requestMeaning describes the request a file makes, whitespace that doesn't
+// change it is left out so files can be compared before and after formatting
+func requestMeaning(ast *HurlAST) string {
+	meaning := strings.Builder{}
+	meaning.WriteString(fmt.Sprintf("%s %s\n", ast.RequestLine.Method, ast.RequestLine.URL))
+
+	for _, header := range ast.Headers {
+		meaning.WriteString(fmt.Sprintf("%s: %s\n", http.CanonicalHeaderKey(header.Name), header.Value))
+	}
+
+	lines := bodyLineTexts(ast)
+	if len(trimBlankLines(lines)) == 0 {
+		if ast.HasBody {
+			meaning.WriteString("\n")
+		}
+		return meaning.String()
+	}
+
+	body := []byte(strings.Join(lines, "\n"))
+	mediaType := ast.MediaType()
+
+	switch {
+	case ast.IsWebSocket():
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		messages, _ := parseWebSocketMessages(sc)
+		meaning.WriteString(fmt.Sprintf("%q", messages))
+
+	case ast.IsGraphQL():
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		graphQLRequest, err := parseGraphQL(sc)
+		if len(graphQLRequest.Variables) > 0 {
+			compacted := bytes.Buffer{}
+			json.Compact(&compacted, graphQLRequest.Variables)
+			graphQLRequest.Variables = compacted.Bytes()
+		}
+		meaning.WriteString(fmt.Sprintf("%q %s %s %v", graphQLRequest.Query, graphQLRequest.Variables, graphQLRequest.OperationName, err))
+
+	case hasFileEmbed(lines):
+		meaning.Write(body)
+
+	case (ast.RequestLine.Method == "GRPC" || lexerForMediaType(mediaType) == "json") && json.Valid(body):
+		compacted := bytes.Buffer{}
+		json.Compact(&compacted, body)
+		meaning.Write(compacted.Bytes())
+
+	case ast.IsMultipart():
+		for _, line := range lines {
+			item, err := parseMultiPartLine(line)
+			meaning.WriteString(fmt.Sprintf("%v %v\n", item, err))
+		}
+
+	case mediaType == "application/x-www-form-urlencoded" && len(lines) == 1 && !strings.Contains(lines[0], "{{"):
+		pairs, err := parseFormPairs(lines[0])
+		if err != nil {
+			meaning.Write(body)
+			break
+		}
+		meaning.WriteString(fmt.Sprintf("%#v", pairs))
+
+	default:
+		meaning.Write(body)
+	}
+
+	return meaning.String()
+}
+
+// RunFmt is the `hurl fmt` command, files are printed formatted unless -w or
+// -check are used and stdin is formatted when there are no files
+func RunFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl fmt [-check] [-w] [files...]")
+		flags.PrintDefaults()
+	}
+	check := flags.Bool("check", false, "list files that aren't formatted and exit with status 1 instead of printing them")
+	write := flags.Bool("w", false, "write formatted files back in place instead of printing them")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hurl fmt: %s\n", err.Error())
+			return 1
+		}
+
+		formatted, err := FormatHurlFile(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hurl fmt: %s\n", fileError("<stdin>", err))
+			return 1
+		}
+
+		if *check {
+			if !bytes.Equal(src, formatted) {
+				fmt.Println("<stdin>")
+				return 1
+			}
+			return 0
+		}
+
+		os.Stdout.Write(formatted)
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		changed, err := formatFile(path, *check, *write)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hurl fmt: %s\n", fileError(path, err))
+			status = 1
+			continue
+		}
+
+		if changed && *check {
+			fmt.Println(path)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// fileError prefixes an error with the file it's in, with the line and column
+// when there is one
+func fileError(path string, err error) string {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("%s:%s", path, syntaxErr.Error())
+	}
+
+	return fmt.Sprintf("%s: %s", path, err.Error())
+}
+
+// formatFile formats the file at path and reports whether formatting
+// changed it
+func formatFile(path string, check bool, write bool) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := FormatHurlFile(src)
+	if err != nil {
+		return false, err
+	}
+
+	changed := !bytes.Equal(src, formatted)
+
+	if check {
+		return changed, nil
+	}
+
+	if !write {
+		_, err = os.Stdout.Write(formatted)
+		return changed, err
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.WriteFile(path, formatted, info.Mode().Perm())
+}
@@ -6,9 +6,6 @@ import (
 	"fmt"
 	"mime/multipart"
 	"net/http"
-	"net/textproto"
-	"os"
-	"strings"
 
 	"github.com/alecthomas/chroma/v2/quick"
 	"github.com/fatih/color"
@@ -77,16 +74,22 @@ func PrettifyJson(j []byte) ([]byte, error) {
 	return prettyJSON.Bytes(), nil
 }
 
-func FormatHeaders(headers http.Header, directionCharacter string) []byte {
+// FormatHeaders prints a line for every value of every header so repeated
+// headers like Set-Cookie stay separate, wireOrder is the order names were
+// received in or nil to sort them
+func FormatHeaders(headers http.Header, directionCharacter string, wireOrder []string) []byte {
 	buffer := bytes.Buffer{}
+	yellow := color.New(color.FgYellow).SprintFunc()
 
-	for name, value := range headers {
-		yellow := color.New(color.FgYellow).SprintFunc()
-		headerVal := strings.Join(value, "")
-
-		formattedHeader := fmt.Sprintf("%s %s: %s\n", directionCharacter, yellow(name), headerVal)
+	for _, name := range orderHeaderNames(headers, wireOrder) {
+		if !isHeaderShown(name) {
+			continue
+		}
 
-		buffer.Write([]byte(formattedHeader))
+		for _, value := range headers[name] {
+			formattedHeader := fmt.Sprintf("%s %s: %s\n", directionCharacter, yellow(name), value)
+			buffer.Write([]byte(formattedHeader))
+		}
 	}
 
 	return buffer.Bytes()
@@ -97,6 +100,15 @@ func FormatFilePathsTitle() string {
 	return fmt.Sprintf("%s\n", title(" body contents outputted to: "))
 }
 
+func FormatGraphQLTitle(title string, isError bool) string {
+	coloredTitle := color.New(color.FgBlack, color.BgGreen).SprintFunc()
+	if isError {
+		coloredTitle = color.New(color.FgBlack, color.BgRed).SprintFunc()
+	}
+
+	return fmt.Sprintf("%s\n", coloredTitle(fmt.Sprintf(" %s ", title)))
+}
+
 func FormatFileEmbed(fileEmbed string) []byte {
 	buffer := bytes.Buffer{}
 
@@ -113,20 +125,36 @@ func FormatFileEmbed(fileEmbed string) []byte {
 func FormatBody(body []byte, mediaType string) ([]byte, error) {
 	buffer := bytes.Buffer{}
 
-	formatter := "" // raw text
-	if mediaType == "application/json" {
-		formatter = "json"
+	// bodies that can't be prettified are shown as they are
+	lexer := lexerForMediaType(mediaType)
+	switch {
+	case lexer == "json":
 		prettified, err := PrettifyJson(body)
-		if err != nil {
-			return []byte{}, nil
+		if err == nil {
+			body = prettified
+		}
+	case lexer == "xml":
+		prettified, err := PrettifyXml(body)
+		if err == nil {
+			body = prettified
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		formatted, err := FormatFormUrlEncoded(body)
+		if err == nil {
+			return formatted, nil
 		}
+	case mediaType == "text/csv":
+		formatted, err := FormatCsv(body)
+		if err == nil {
+			return formatted, nil
+		}
+	}
 
-		body = prettified
-	} else if mediaType == "text/html" {
-		formatter = "html"
+	if color.NoColor {
+		return body, nil
 	}
 
-	err := quick.Highlight(&buffer, string(body), formatter, "terminal", "")
+	err := quick.Highlight(&buffer, string(body), lexer, highlightFormatter, highlightStyle)
 	if err != nil {
 		return []byte{}, nil
 	}
@@ -158,22 +186,7 @@ func FormatMultiPart(multipartItems []MultiPartItem, boundary string) ([]byte, e
 
 	for _, multiPartItem := range multipartItems {
 		if multiPartItem.IsFilePath {
-			file, err := os.Stat(multiPartItem.Value)
-			if err != nil {
-				return []byte{}, err
-			}
-
-			fileData, err := os.ReadFile(multiPartItem.Value)
-			if err != nil {
-				return []byte{}, err
-			}
-
-			mimeHeader := make(textproto.MIMEHeader)
-
-			mimeHeader.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"; filename=\"%s\"", multiPartItem.Name, file.Name()))
-			mimeHeader.Set("Content-Type", http.DetectContentType(fileData))
-
-			part, err := writer.CreatePart(mimeHeader)
+			part, _, err := createMultipartFilePart(writer, multiPartItem)
 			if err != nil {
 				return []byte{}, err
 			}
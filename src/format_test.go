@@ -1 +1,193 @@
 package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// parsedRequest is what ParseHurlFile makes of a file. Header names are case
+// insensitive, JSON bodies are compacted and forms decoded since changing
+// those is the point of formatting
+func parsedRequest(t *testing.T, src string) string {
+	t.Helper()
+
+	hurlFile, err := ParseHurlFile(strings.NewReader(src))
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	headers := http.Header{}
+	for name, value := range hurlFile.Headers {
+		headers.Set(name, value)
+	}
+
+	body := fmt.Sprintf("%q", hurlFile.Body)
+	compacted := bytes.Buffer{}
+	if json.Compact(&compacted, hurlFile.Body) == nil {
+		body = compacted.String()
+	}
+	if headers.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(strings.TrimSpace(string(hurlFile.Body)))
+		if err == nil {
+			body = fmt.Sprint(form)
+		}
+	}
+
+	return fmt.Sprintf("%s %s\n%v\n%s\n%q\n%v\n%t", hurlFile.Method, hurlFile.URL.String(), headers, body, hurlFile.FileEmbed, hurlFile.MultipartFormData, hurlFile.IsGraphQL)
+}
+
+func TestFormatHurlFile(t *testing.T) {
+	t.Setenv("BASE_URL", "https://example.com")
+	t.Setenv("TOKEN", "secret")
+	t.Setenv("ID", "42")
+
+	tests := []struct {
+		name      string
+		src       string
+		formatted string
+	}{
+		{
+			"request line and headers",
+			"GET https://example.com/users\ncontent-type:application/json\n",
+			"GET https://example.com/users\nContent-Type: application/json\n",
+		},
+		{
+			"json body",
+			"POST https://example.com/users\nContent-Type: application/json\n\n{\"name\":\"bob\",\"tags\":[\"a\",\"b\"]}\n",
+			"POST https://example.com/users\nContent-Type: application/json\n\n{\n  \"name\": \"bob\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ]\n}\n",
+		},
+		{
+			"text body keeps its blank lines",
+			"POST https://example.com/notes\nContent-Type: text/plain\n\nfirst\n\n\nsecond\n\n",
+			"POST https://example.com/notes\nContent-Type: text/plain\n\nfirst\n\n\nsecond\n\n",
+		},
+		{
+			"blank line that starts a body",
+			"POST https://example.com/ping\n\n",
+			"POST https://example.com/ping\n\n",
+		},
+		{
+			"form body",
+			"POST https://example.com/login\nContent-Type: application/x-www-form-urlencoded\n\nuser=bob+smith&note=a%2fb\n",
+			"POST https://example.com/login\nContent-Type: application/x-www-form-urlencoded\n\nuser=bob+smith&note=a%2Fb\n",
+		},
+		{
+			"multipart lines are aligned",
+			"POST https://example.com/upload\nContent-Type: multipart/form-data\n\nform-data; name=\"id\"; value=\"1\"\nform-data;name=\"description\";value=\"a file\"\n",
+			"POST https://example.com/upload\nContent-Type: multipart/form-data\n\nform-data; name=\"id\";          value=\"1\"\nform-data; name=\"description\"; value=\"a file\"\n",
+		},
+		{
+			"multipart with a boundary is a raw body",
+			"POST https://example.com/upload\nContent-Type: multipart/form-data; boundary=x\n\nform-data;name=\"id\";value=\"1\"\n",
+			"POST https://example.com/upload\nContent-Type: multipart/form-data; boundary=x\n\nform-data;name=\"id\";value=\"1\"\n",
+		},
+		{
+			"templates are left alone",
+			"GET {{BASE_URL}}/users/{{ID}}\nauthorization: Bearer {{TOKEN}}\ncontent-type: application/json\n\n{\"id\": {{ID}}}\n",
+			"GET {{BASE_URL}}/users/{{ID}}\nAuthorization: Bearer {{TOKEN}}\nContent-Type: application/json\n\n{\"id\": {{ID}}}\n",
+		},
+		{
+			"form with templates",
+			"POST {{BASE_URL}}/login\nContent-Type: application/x-www-form-urlencoded\n\ntoken={{TOKEN}}&a=b%2fc\n",
+			"POST {{BASE_URL}}/login\nContent-Type: application/x-www-form-urlencoded\n\ntoken={{TOKEN}}&a=b%2fc\n",
+		},
+		{
+			"comment lines in a body are body text",
+			"POST https://example.com/script\nContent-Type: text/x-shellscript\n\n# says hello\necho hello\n",
+			"POST https://example.com/script\nContent-Type: text/x-shellscript\n\n# says hello\necho hello\n",
+		},
+		{
+			"json with a comment isn't indented",
+			"POST https://example.com/users\nContent-Type: application/json\n\n# the new user\n{\"name\":\"bob\"}\n",
+			"POST https://example.com/users\nContent-Type: application/json\n\n# the new user\n{\"name\":\"bob\"}\n",
+		},
+		{
+			"file embed",
+			"POST https://example.com/upload\nContent-Type: application/json\n\n@file=./payload.json\n",
+			"POST https://example.com/upload\nContent-Type: application/json\n\n@file=./payload.json\n",
+		},
+		{
+			"graphql",
+			"POST https://example.com/graphql\n\nGRAPHQL\nquery { user(id: 1) { name } }\n\n@variables\n{\"id\":1}\n",
+			"POST https://example.com/graphql\n\nGRAPHQL\nquery { user(id: 1) { name } }\n\n@variables\n{\n  \"id\": 1\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := FormatHurlFile([]byte(test.src))
+			if err != nil {
+				t.Fatalf("expected the file to format, got %s", err)
+			}
+
+			if string(formatted) != test.formatted {
+				t.Errorf("expected\n%s\ngot\n%s", test.formatted, formatted)
+			}
+
+			again, err := FormatHurlFile(formatted)
+			if err != nil || !bytes.Equal(again, formatted) {
+				t.Errorf("formatting again changed the file to\n%s\n%v", again, err)
+			}
+
+			before, after := parsedRequest(t, test.src), parsedRequest(t, string(formatted))
+			if before != after {
+				t.Errorf("formatting changed the request from\n%s\nto\n%s", before, after)
+			}
+		})
+	}
+}
+
+func TestFormatHurlFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"missing request line",
+			"\n",
+			"1:1: missing request line",
+		},
+		{
+			"invalid method",
+			"FETCH https://example.com\n",
+			"1:1: invalid method: FETCH",
+		},
+		{
+			"malformed header",
+			"GET https://example.com\nAccept\n",
+			"2:1: header is malformed, expected [header]: [value]: `Accept`",
+		},
+		{
+			"blank line in a multipart body",
+			"POST https://example.com/upload\nContent-Type: multipart/form-data\n\nform-data; name=\"a\"; value=\"1\"\n\nform-data; name=\"b\"; value=\"2\"\n",
+			"5:1: expected form-data; name=\"[name]\"; value=\"[value]\", found \"\"",
+		},
+		{
+			"malformed multipart line",
+			"POST https://example.com/upload\nContent-Type: multipart/form-data\n\nname=\"a\"\n",
+			`4:1: expected form-data; name="[name]"; value="[value]", found "name="a""`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := FormatHurlFile([]byte(test.src))
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+
+			// files that don't format don't parse either
+			if strings.Contains(test.name, "multipart") {
+				if _, err := ParseHurlFile(strings.NewReader(test.src)); err == nil {
+					t.Errorf("expected ParseHurlFile to reject the file too")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	GRAPHQL_BODY_TAG           = "GRAPHQL"
+	GRAPHQL_VARIABLES_TAG      = "@variables"
+	GRAPHQL_OPERATION_NAME_TAG = "@operationName"
+)
+
+type GraphQLRequest struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	OperationName string          `json:"operationName,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors json.RawMessage `json:"errors"`
+}
+
+// isGraphQLBody is whether a body starts with the GRAPHQL line that marks it as
+// a GraphQL query. A GraphQL document can't start with that word, so bodies
+// sent as application/graphql as they are aren't mistaken for one
+func isGraphQLBody(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return strings.TrimSpace(line) == GRAPHQL_BODY_TAG
+		}
+	}
+
+	return false
+}
+
+// parseGraphQL reads a GraphQL body, the GRAPHQL line is followed by the raw
+// query, an optional `@variables` block of JSON and an `@operationName=` tag
+func parseGraphQL(sc *bufio.Scanner) (GraphQLRequest, error) {
+	query := bytes.Buffer{}
+	variables := bytes.Buffer{}
+	operationName := ""
+
+	tagFound := false
+	inVariables := false
+	variablesFound := false
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if !tagFound && trimmed == GRAPHQL_BODY_TAG {
+			tagFound = true
+			continue
+		}
+		if trimmed != "" {
+			tagFound = true
+		}
+
+		if trimmed == GRAPHQL_VARIABLES_TAG {
+			if variablesFound {
+				return GraphQLRequest{}, errors.New("more than 1 @variables block found")
+			}
+
+			variablesFound = true
+			inVariables = true
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, GRAPHQL_OPERATION_NAME_TAG+"=") {
+			if operationName != "" {
+				return GraphQLRequest{}, errors.New("more than 1 @operationName found")
+			}
+
+			operationName = strings.TrimSpace(strings.TrimPrefix(trimmed, GRAPHQL_OPERATION_NAME_TAG+"="))
+			inVariables = false
+			continue
+		}
+
+		if inVariables {
+			variables.WriteString(line)
+			variables.WriteByte('\n')
+		} else {
+			query.WriteString(line)
+			query.WriteByte('\n')
+		}
+	}
+	if sc.Err() != nil {
+		return GraphQLRequest{}, sc.Err()
+	}
+
+	graphQLRequest := GraphQLRequest{
+		Query:         strings.TrimSpace(query.String()),
+		OperationName: operationName,
+	}
+
+	if graphQLRequest.Query == "" {
+		return GraphQLRequest{}, errors.New("GraphQL query is empty")
+	}
+
+	trimmedVariables := bytes.TrimSpace(variables.Bytes())
+	if len(trimmedVariables) > 0 {
+		if trimmedVariables[0] != '{' || !json.Valid(trimmedVariables) {
+			return GraphQLRequest{}, errors.New("@variables must be a JSON object")
+		}
+
+		graphQLRequest.Variables = trimmedVariables
+	}
+
+	return graphQLRequest, nil
+}
+
+// setGraphQLRequest wraps the query in the JSON envelope GraphQL servers expect,
+// as the request body for POST or as query parameters for GET
+func (h *HurlFile) setGraphQLRequest(graphQLRequest GraphQLRequest) error {
+	h.IsGraphQL = true
+
+	if h.Method == "GET" {
+		query := h.URL.Query()
+		query.Set("query", graphQLRequest.Query)
+		if len(graphQLRequest.Variables) > 0 {
+			query.Set("variables", string(graphQLRequest.Variables))
+		}
+		if graphQLRequest.OperationName != "" {
+			query.Set("operationName", graphQLRequest.OperationName)
+		}
+		h.URL.RawQuery = query.Encode()
+
+		return nil
+	}
+
+	body, err := json.Marshal(graphQLRequest)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := h.Headers[h.headerName("Content-Type")]; !exists {
+		h.Headers["Content-Type"] = "application/json"
+	}
+	h.Body = body
+
+	return nil
+}
+
+// FormatGraphQLBody highlights the data and errors of a GraphQL response
+// separately, returns how many errors the response contains
+func FormatGraphQLBody(body []byte) ([]byte, int, error) {
+	var graphQLRes graphQLResponse
+	err := json.Unmarshal(body, &graphQLRes)
+	if err != nil {
+		return []byte{}, 0, fmt.Errorf("response is not a GraphQL response: %w", err)
+	}
+
+	buffer := bytes.Buffer{}
+
+	if len(graphQLRes.Data) > 0 && string(graphQLRes.Data) != "null" {
+		buffer.WriteString(FormatGraphQLTitle("data", false))
+
+		data, err := FormatBody(graphQLRes.Data, "application/json")
+		if err != nil {
+			return []byte{}, 0, err
+		}
+		buffer.Write(data)
+	}
+
+	var errs []json.RawMessage
+	if len(graphQLRes.Errors) > 0 && string(graphQLRes.Errors) != "null" {
+		err := json.Unmarshal(graphQLRes.Errors, &errs)
+		if err != nil {
+			return []byte{}, 0, fmt.Errorf("GraphQL errors must be a list: %w", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		if buffer.Len() > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(FormatGraphQLTitle("errors", true))
+
+		formattedErrs, err := FormatBody(graphQLRes.Errors, "application/json")
+		if err != nil {
+			return []byte{}, 0, err
+		}
+		buffer.Write(formattedErrs)
+	}
+
+	return buffer.Bytes(), len(errs), nil
+}
@@ -0,0 +1,312 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	GRPC_FRAME_HEADER_LENGTH = 5
+	GRPC_FLAG_COMPRESSED     = 0x01
+	CONNECT_FLAG_END_STREAM  = 0x02
+
+	// the default limit of grpc-go, the length prefix allows up to 4 GiB
+	GRPC_MAX_MESSAGE_SIZE = 4 << 20
+)
+
+// GRPCCall is a request file resolved against a descriptor set, it knows how
+// to encode the JSON body and decode the responses for its method
+type GRPCCall struct {
+	Method   protoreflect.MethodDescriptor
+	Protocol string
+}
+
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type connectEndStream struct {
+	Error *connectError `json:"error"`
+}
+
+// IsGRPC reports whether the request file describes a gRPC call, the URL path
+// of these is the fully qualified service and method e.g. /pkg.Service/Method
+func (h *HurlFile) IsGRPC() bool {
+	return h.Method == "GRPC"
+}
+
+func loadProtoset(path string) (*protoregistry.Files, error) {
+	protosetBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileDescriptorSet descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(protosetBytes, &fileDescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protoset %s: %w", path, err)
+	}
+
+	return protodesc.NewFiles(&fileDescriptorSet)
+}
+
+func findMethod(files *protoregistry.Files, fullMethod string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, found := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !found || serviceName == "" || methodName == "" {
+		return nil, fmt.Errorf("gRPC URL path must be /<package.Service>/<Method>, got: %s", fullMethod)
+	}
+
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found in protoset", serviceName)
+	}
+
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+
+	if method.IsStreamingClient() {
+		return nil, fmt.Errorf("client streaming method %s is not supported", methodName)
+	}
+
+	return method, nil
+}
+
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, GRPC_FRAME_HEADER_LENGTH, GRPC_FRAME_HEADER_LENGTH+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// readGRPCFrame reads a single length prefixed message and its flags, returns
+// io.EOF once the stream has no more messages
+func readGRPCFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, GRPC_FRAME_HEADER_LENGTH)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	flags := header[0]
+	if flags&GRPC_FLAG_COMPRESSED != 0 {
+		return 0, nil, errors.New("compressed gRPC messages are not supported")
+	}
+	if flags&^CONNECT_FLAG_END_STREAM != 0 {
+		return 0, nil, fmt.Errorf("unknown gRPC message flags: 0x%02x", flags)
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > GRPC_MAX_MESSAGE_SIZE {
+		return 0, nil, fmt.Errorf("gRPC message of %d bytes is over the %d byte limit", length, GRPC_MAX_MESSAGE_SIZE)
+	}
+
+	message := make([]byte, length)
+	_, err = io.ReadFull(r, message)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return flags, message, nil
+}
+
+// NewGRPCCall looks up the method of the request file in the descriptor set
+func (h *HurlFile) NewGRPCCall(protoset string, protocol string) (*GRPCCall, error) {
+	if protoset == "" {
+		return nil, errors.New("gRPC requests need a descriptor set, set -protoset or \"protoset\" in hurl.json")
+	}
+
+	files, err := loadProtoset(protoset)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := findMethod(files, h.URL.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GRPCCall{method, protocol}, nil
+}
+
+// NewRequest encodes the JSON body of the request file as the method's input
+// message and frames it for the chosen protocol
+func (g *GRPCCall) NewRequest(h *HurlFile) (*http.Request, error) {
+	body := bytes.TrimSpace(h.Body)
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+
+	input := dynamicpb.NewMessage(g.Method.Input())
+	err := protojson.Unmarshal(body, input)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode body as %s: %w", g.Method.Input().FullName(), err)
+	}
+
+	message, err := proto.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	for name, val := range h.Headers {
+		header.Set(name, val)
+	}
+
+	// the request is sent as protobuf, the JSON body is only for writing it
+	if g.Protocol == "connect" {
+		header.Set("Content-Type", "application/proto")
+		header.Set("Connect-Protocol-Version", "1")
+		if g.Method.IsStreamingServer() {
+			header.Set("Content-Type", "application/connect+proto")
+			message = grpcFrame(message)
+		}
+	} else {
+		header.Set("Content-Type", "application/grpc+proto")
+		header.Set("TE", "trailers")
+		message = grpcFrame(message)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL.String(), bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	return req, nil
+}
+
+// Client returns a client able to speak HTTP/2 without TLS, which gRPC and
+// Connect servers commonly listen on locally. Connections aren't wrapped for
+// -header-order wire or -trace as that only offers HTTP/1.1 over TLS
+func (g *GRPCCall) Client(u url.URL, config HurlConfig) *http.Client {
+	if u.Scheme == "https" {
+		return &http.Client{Transport: newTransport(config)}
+	}
+
+	dialContext := NewDialContext(config)
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (g *GRPCCall) decodeMessage(message []byte) ([]byte, error) {
+	output := dynamicpb.NewMessage(g.Method.Output())
+	err := proto.Unmarshal(message, output)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode response as %s: %w", g.Method.Output().FullName(), err)
+	}
+
+	return protojson.Marshal(output)
+}
+
+// DecodeResponse reads every message of the response as JSON, an error is
+// returned for non OK gRPC statuses and Connect errors
+func (g *GRPCCall) DecodeResponse(res *http.Response) ([][]byte, error) {
+	if g.Protocol == "connect" && !g.Method.IsStreamingServer() {
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusOK {
+			var connectErr connectError
+			if json.Unmarshal(body, &connectErr) != nil {
+				return nil, fmt.Errorf("connect request failed with %s", res.Status)
+			}
+			return nil, fmt.Errorf("connect error %s: %s", connectErr.Code, connectErr.Message)
+		}
+
+		message, err := g.decodeMessage(body)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{message}, nil
+	}
+
+	messages := [][]byte{}
+	for {
+		flags, frame, err := readGRPCFrame(res.Body)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// connect streams end with a JSON message holding the status
+		if g.Protocol == "connect" && flags&CONNECT_FLAG_END_STREAM != 0 {
+			var endStream connectEndStream
+			err := json.Unmarshal(frame, &endStream)
+			if err != nil {
+				return messages, fmt.Errorf("malformed connect end of stream message: %w", err)
+			}
+
+			if endStream.Error != nil {
+				return messages, fmt.Errorf("connect error %s: %s", endStream.Error.Code, endStream.Error.Message)
+			}
+			break
+		}
+
+		message, err := g.decodeMessage(frame)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	if g.Protocol == "connect" {
+		return messages, nil
+	}
+
+	// trailers only responses put the status in the headers
+	status := res.Trailer.Get("Grpc-Status")
+	statusMessage := res.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = res.Header.Get("Grpc-Status")
+		statusMessage = res.Header.Get("Grpc-Message")
+	}
+
+	if status != "" && status != "0" {
+		decodedMessage, err := url.PathUnescape(statusMessage)
+		if err != nil {
+			decodedMessage = statusMessage
+		}
+		return messages, fmt.Errorf("gRPC status %s: %s", status, decodedMessage)
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,356 @@
+package src
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// greeterFile describes a test.Greeter service with a unary and a server
+// streaming method, the way protoc would write it in a descriptor set
+func greeterFile() *descriptorpb.FileDescriptorProto {
+	stringField := func(name string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(1),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("greeter.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("HelloRequest"), Field: []*descriptorpb.FieldDescriptorProto{stringField("name")}},
+			{Name: proto.String("HelloReply"), Field: []*descriptorpb.FieldDescriptorProto{stringField("message")}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("SayHello"), InputType: proto.String(".test.HelloRequest"), OutputType: proto.String(".test.HelloReply")},
+				{Name: proto.String("SayHellos"), InputType: proto.String(".test.HelloRequest"), OutputType: proto.String(".test.HelloReply"), ServerStreaming: proto.Bool(true)},
+			},
+		}},
+	}
+}
+
+func writeProtoset(t *testing.T) string {
+	t.Helper()
+
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{greeterFile()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "greeter.protoset")
+	err = os.WriteFile(path, set, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// greeterServer answers test.Greeter calls over h2c with both the gRPC and
+// the Connect protocol. Names starting with "fail" get an error back
+type greeterServer struct {
+	t      *testing.T
+	input  protoreflect.MessageDescriptor
+	output protoreflect.MessageDescriptor
+}
+
+func newGreeterServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(h2c.NewHandler(newGreeter(t), &http2.Server{}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newGreeterTLSServer answers over HTTP/2 with TLS, the way gRPC servers do
+// outside of development
+func newGreeterTLSServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(newGreeter(t))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newGreeter(t *testing.T) *greeterServer {
+	file, err := protodesc.NewFile(greeterFile(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &greeterServer{t, file.Messages().ByName("HelloRequest"), file.Messages().ByName("HelloReply")}
+}
+
+func (g *greeterServer) name(message []byte) string {
+	input := dynamicpb.NewMessage(g.input)
+	err := proto.Unmarshal(message, input)
+	if err != nil {
+		g.t.Error(err)
+	}
+
+	return input.Get(g.input.Fields().ByName("name")).String()
+}
+
+func (g *greeterServer) reply(message string) []byte {
+	output := dynamicpb.NewMessage(g.output)
+	output.Set(g.output.Fields().ByName("message"), protoreflect.ValueOfString(message))
+	encoded, err := proto.Marshal(output)
+	if err != nil {
+		g.t.Error(err)
+	}
+
+	return encoded
+}
+
+func connectFrame(flags byte, message []byte) []byte {
+	frame := grpcFrame(message)
+	frame[0] = flags
+	return frame
+}
+
+func (g *greeterServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		http.Error(w, fmt.Sprintf("expected HTTP/2, got %s", r.Proto), http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		g.t.Error(err)
+		return
+	}
+
+	streaming := strings.HasSuffix(r.URL.Path, "/SayHellos")
+
+	switch r.Header.Get("Content-Type") {
+	case "application/proto":
+		name := g.name(body)
+		if strings.HasPrefix(name, "fail") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code": "not_found", "message": "no one called fail"}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/proto")
+		w.Write(g.reply("hello " + name))
+
+	case "application/connect+proto":
+		_, message, err := readGRPCFrame(bytes.NewReader(body))
+		if err != nil {
+			g.t.Error(err)
+			return
+		}
+		name := g.name(message)
+
+		w.Header().Set("Content-Type", "application/connect+proto")
+		w.Write(connectFrame(0, g.reply("hello "+name)))
+		if strings.HasPrefix(name, "fail") {
+			w.Write(connectFrame(CONNECT_FLAG_END_STREAM, []byte(`{"error": {"code": "not_found", "message": "no one called fail"}}`)))
+			return
+		}
+		w.Write(connectFrame(0, g.reply("hello again "+name)))
+		w.Write(connectFrame(CONNECT_FLAG_END_STREAM, []byte(`{}`)))
+
+	case "application/grpc+proto":
+		_, message, err := readGRPCFrame(bytes.NewReader(body))
+		if err != nil {
+			g.t.Error(err)
+			return
+		}
+		name := g.name(message)
+
+		w.Header().Set("Content-Type", "application/grpc+proto")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		if strings.HasPrefix(name, "fail") {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "no%20one%20called%20fail")
+			return
+		}
+
+		w.Write(grpcFrame(g.reply("hello " + name)))
+		if streaming {
+			w.Write(grpcFrame(g.reply("hello again " + name)))
+		}
+		w.Header().Set("Grpc-Status", "0")
+
+	default:
+		http.Error(w, "unexpected Content-Type", http.StatusUnsupportedMediaType)
+	}
+}
+
+// callGreeter sends a request file for a test.Greeter method the way hurl
+// does and returns the messages it got back
+func callGreeter(t *testing.T, server *httptest.Server, config HurlConfig, protocol string, method string, name string) ([]string, error) {
+	t.Helper()
+
+	src := fmt.Sprintf("GRPC %s/test.Greeter/%s\n\n{\"name\": %q}\n", server.URL, method, name)
+	hurlFile, err := ParseHurlFile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call, err := hurlFile.NewGRPCCall(writeProtoset(t), protocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := call.NewRequest(hurlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := call.Client(hurlFile.URL, config).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.ProtoMajor != 2 {
+		t.Errorf("expected the call to be made over HTTP/2, got %s", res.Proto)
+	}
+
+	messages, err := call.DecodeResponse(res)
+
+	// protojson doesn't promise stable whitespace
+	decoded := []string{}
+	for _, message := range messages {
+		compacted := bytes.Buffer{}
+		json.Compact(&compacted, message)
+		decoded = append(decoded, compacted.String())
+	}
+
+	return decoded, err
+}
+
+func TestGRPCCalls(t *testing.T) {
+	server := newGreeterServer(t)
+
+	tests := []struct {
+		protocol string
+		method   string
+		name     string
+		messages []string
+		err      string
+	}{
+		{"grpc", "SayHello", "bob", []string{`{"message":"hello bob"}`}, ""},
+		{"grpc", "SayHellos", "bob", []string{`{"message":"hello bob"}`, `{"message":"hello again bob"}`}, ""},
+		{"grpc", "SayHello", "fail", []string{}, "gRPC status 5: no one called fail"},
+		{"connect", "SayHello", "bob", []string{`{"message":"hello bob"}`}, ""},
+		{"connect", "SayHellos", "bob", []string{`{"message":"hello bob"}`, `{"message":"hello again bob"}`}, ""},
+		{"connect", "SayHello", "fail", nil, "connect error not_found: no one called fail"},
+		{"connect", "SayHellos", "fail", []string{`{"message":"hello fail"}`}, "connect error not_found: no one called fail"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %s %s", test.protocol, test.method, test.name), func(t *testing.T) {
+			messages, err := callGreeter(t, server, HurlConfig{}, test.protocol, test.method, test.name)
+
+			errMessage := ""
+			if err != nil {
+				errMessage = err.Error()
+			}
+			if errMessage != test.err {
+				t.Errorf("expected error %q, got %q", test.err, errMessage)
+			}
+
+			if test.messages != nil && strings.Join(messages, "\n") != strings.Join(test.messages, "\n") {
+				t.Errorf("expected messages %v, got %v", test.messages, messages)
+			}
+		})
+	}
+}
+
+// connections aren't wrapped for -header-order wire, which would only offer
+// HTTP/1.1 over TLS
+func TestGRPCCallsOverTLS(t *testing.T) {
+	server := newGreeterTLSServer(t)
+
+	err := SetHeaderDisplay("wire", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetHeaderDisplay("sorted", nil, nil) })
+
+	for _, protocol := range []string{"grpc", "connect"} {
+		t.Run(protocol, func(t *testing.T) {
+			messages, err := callGreeter(t, server, HurlConfig{Insecure: true}, protocol, "SayHello", "bob")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Join(messages, "\n") != `{"message":"hello bob"}` {
+				t.Errorf("expected the reply, got %v", messages)
+			}
+		})
+	}
+}
+
+func TestGRPCFrameRoundTrip(t *testing.T) {
+	frame := grpcFrame([]byte("message"))
+	if binary.BigEndian.Uint32(frame[1:GRPC_FRAME_HEADER_LENGTH]) != uint32(len("message")) {
+		t.Fatalf("frame length prefix is wrong: %v", frame[:GRPC_FRAME_HEADER_LENGTH])
+	}
+
+	flags, message, err := readGRPCFrame(bytes.NewReader(frame))
+	if err != nil || flags != 0 || string(message) != "message" {
+		t.Fatalf("expected the message back, got %d %q %v", flags, message, err)
+	}
+
+	_, _, err = readGRPCFrame(bytes.NewReader(nil))
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestReadGRPCFrameErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		err   string
+	}{
+		{"compressed", []byte{GRPC_FLAG_COMPRESSED, 0, 0, 0, 1, 'a'}, "compressed gRPC messages are not supported"},
+		{"unknown flags", []byte{0x80, 0, 0, 0, 1, 'a'}, "unknown gRPC message flags: 0x80"},
+		{"over the size limit", []byte{0, 0xff, 0xff, 0xff, 0xff}, "gRPC message of 4294967295 bytes is over the 4194304 byte limit"},
+		{"cut off", []byte{0, 0, 0, 0, 5, 'a'}, "unexpected EOF"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := readGRPCFrame(bytes.NewReader(test.frame))
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+
+	// the end of a Connect stream is flagged
+	flags, _, err := readGRPCFrame(bytes.NewReader(connectFrame(CONNECT_FLAG_END_STREAM, []byte("{}"))))
+	if err != nil || flags != CONNECT_FLAG_END_STREAM {
+		t.Fatalf("expected the end stream flag, got %d %v", flags, err)
+	}
+}
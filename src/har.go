@@ -0,0 +1,487 @@
+package src
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// the parts of HAR 1.2 hurl writes and reads, see
+// http://www.softwareishard.com/blog/har-12-spec/
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+type harPostData struct {
+	MimeType string     `json:"mimeType"`
+	Text     string     `json:"text"`
+	Params   []harParam `json:"params,omitempty"`
+}
+
+type harParam struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type harContent struct {
+	Size        int    `json:"size"`
+	Compression int    `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// harTimings are in milliseconds, -1 when they don't apply like dns for a
+// reused connection
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// response bodies larger than this aren't kept, so big downloads aren't
+// held in memory
+const MAX_HAR_BODY_SIZE = 16 << 20
+
+// where -har records to, nil when recording is off
+var harRecording *harFile
+var harOutput *os.File
+var harMu sync.Mutex
+
+// the response bodies of entries still being read
+var harBodies []*harBody
+
+// OpenHar starts recording every request and response, including redirects,
+// to a HAR file written when CloseHar is called
+func OpenHar(path string, version string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	harOutput = file
+	harRecording = &harFile{harLog{
+		Version: "1.2",
+		Creator: harCreator{"hurl", version},
+		Entries: []*harEntry{},
+	}}
+
+	return nil
+}
+
+// CloseHar writes the recording, the rest of any response bodies that weren't
+// read, like with -headers-only, are read first so the responses are complete
+func CloseHar() error {
+	harMu.Lock()
+	bodies := harBodies
+	harBodies = nil
+	harMu.Unlock()
+
+	for _, body := range bodies {
+		io.Copy(io.Discard, body)
+		body.Close()
+	}
+
+	harMu.Lock()
+	defer harMu.Unlock()
+
+	if harOutput == nil {
+		return nil
+	}
+
+	// URLs are kept readable rather than with & written as \u0026
+	encoder := json.NewEncoder(harOutput)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(harRecording)
+	if closeErr := harOutput.Close(); err == nil {
+		err = closeErr
+	}
+
+	harOutput = nil
+	harRecording = nil
+
+	return err
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	names := []string{}
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := []harNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, harNameValue{name, value})
+		}
+	}
+
+	return headers
+}
+
+func harCookies(cookies []*http.Cookie) []harCookie {
+	harCookies := []harCookie{}
+	for _, cookie := range cookies {
+		harCookie := harCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Expires.IsZero() {
+			expires := cookie.Expires
+			harCookie.Expires = &expires
+		}
+		harCookies = append(harCookies, harCookie)
+	}
+
+	return harCookies
+}
+
+// milliseconds between two times, -1 if either didn't happen
+func harDuration(start time.Time, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return -1
+	}
+
+	return float64(end.Sub(start).Microseconds()) / 1000
+}
+
+// harTransport records every round trip made through it, each redirect is an
+// entry of its own like in browsers
+type harTransport struct {
+	base http.RoundTripper
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := &harEntry{StartedDateTime: time.Now()}
+	times := &harTimes{start: entry.StartedDateTime}
+
+	// the request body is kept as it's sent
+	requestBody := &bytes.Buffer{}
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(req.Body, requestBody), req.Body}
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), times.clientTrace(entry)))
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if times.firstByte.IsZero() {
+		times.firstByte = time.Now()
+	}
+
+	entry.Request = newHarRequest(req, res, requestBody.Bytes())
+	entry.Response = harResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Cookies:     harCookies(res.Cookies()),
+		Headers:     harHeaders(res.Header),
+		RedirectURL: res.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+
+	// the entry is completed once the body has been read, which may be
+	// while it's streamed to a -o file
+	body := &harBody{ReadCloser: res.Body, entry: entry, times: times, header: res.Header}
+	res.Body = body
+
+	harMu.Lock()
+	if harRecording != nil {
+		harRecording.Log.Entries = append(harRecording.Log.Entries, entry)
+		harBodies = append(harBodies, body)
+	}
+	harMu.Unlock()
+
+	return res, nil
+}
+
+func newHarRequest(req *http.Request, res *http.Response, body []byte) harRequest {
+	// the Host header isn't in req.Header but is sent
+	header := req.Header.Clone()
+	header.Set("Host", req.Host)
+	if req.Host == "" {
+		header.Set("Host", req.URL.Host)
+	}
+
+	query := []harNameValue{}
+	values := req.URL.Query()
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range values[name] {
+			query = append(query, harNameValue{name, value})
+		}
+	}
+
+	// a server can answer with an older version than the request was sent
+	// with, but req.Proto stays HTTP/1.1 when the transport uses HTTP/2
+	httpVersion := req.Proto
+	if res.ProtoMajor == 2 {
+		httpVersion = res.Proto
+	}
+
+	harReq := harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: httpVersion,
+		Cookies:     harCookies(req.Cookies()),
+		Headers:     harHeaders(header),
+		QueryString: query,
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+
+	if len(body) > 0 {
+		harReq.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+
+	return harReq
+}
+
+// harTimes are when each phase of a round trip started and ended
+type harTimes struct {
+	start        time.Time
+	gotConn      time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func (h *harTimes) clientTrace(entry *harEntry) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			h.gotConn = time.Now()
+			host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String())
+			if err == nil {
+				entry.ServerIPAddress = host
+			}
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { h.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { h.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { h.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { h.connectDone = time.Now() },
+		TLSHandshakeStart:    func() { h.tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { h.tlsDone = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { h.wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { h.firstByte = time.Now() },
+	}
+}
+
+// timings splits the round trip into phases, connect includes the TLS
+// handshake as HAR expects
+func (h *harTimes) timings(end time.Time) harTimings {
+	timings := harTimings{
+		DNS:     harDuration(h.dnsStart, h.dnsDone),
+		Connect: harDuration(h.connectStart, h.tlsDone),
+		SSL:     harDuration(h.tlsStart, h.tlsDone),
+		Send:    harDuration(h.gotConn, h.wroteRequest),
+		Wait:    harDuration(h.wroteRequest, h.firstByte),
+		Receive: harDuration(h.firstByte, end),
+	}
+	if h.tlsDone.IsZero() {
+		timings.Connect = harDuration(h.connectStart, h.connectDone)
+	}
+
+	// waiting for a connection, less the time spent making one
+	timings.Blocked = harDuration(h.start, h.gotConn)
+	for _, phase := range []float64{timings.DNS, timings.Connect} {
+		if phase > 0 {
+			timings.Blocked -= phase
+		}
+	}
+	timings.Blocked = math.Max(math.Round(timings.Blocked*1000)/1000, 0)
+
+	return timings
+}
+
+// harBody keeps the response body as it's read and completes the entry once
+// it has all been read, reading it failed or it's closed
+type harBody struct {
+	io.ReadCloser
+	received bytes.Buffer
+	size     int
+	err      error
+	entry    *harEntry
+	times    *harTimes
+	header   http.Header
+	once     sync.Once
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	if b.size <= MAX_HAR_BODY_SIZE {
+		b.received.Write(p[:n])
+	}
+
+	if err != nil {
+		if err != io.EOF {
+			b.err = err
+		}
+		b.complete()
+	}
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.complete()
+	return b.ReadCloser.Close()
+}
+
+func (b *harBody) complete() {
+	b.once.Do(func() {
+		harMu.Lock()
+		defer harMu.Unlock()
+
+		b.entry.Timings = b.times.timings(time.Now())
+		for _, phase := range []float64{b.entry.Timings.Blocked, b.entry.Timings.DNS, b.entry.Timings.Connect, b.entry.Timings.Send, b.entry.Timings.Wait, b.entry.Timings.Receive} {
+			if phase > 0 {
+				b.entry.Time += phase
+			}
+		}
+		b.entry.Time = math.Round(b.entry.Time*1000) / 1000
+
+		b.entry.Response.BodySize = b.size
+		switch {
+		case b.size > MAX_HAR_BODY_SIZE:
+			b.entry.Response.Content = harContent{Size: b.size, MimeType: b.header.Get("Content-Type")}
+			b.entry.Response.Content.Comment = fmt.Sprintf("body of %s not recorded, it's larger than %s", formatBytes(int64(b.size)), formatBytes(MAX_HAR_BODY_SIZE))
+		default:
+			b.entry.Response.Content = harResponseContent(b.received.Bytes(), b.header)
+		}
+
+		if b.err != nil {
+			b.entry.Response.Content.Comment = fmt.Sprintf("body incomplete after %d bytes: %s", b.size, b.err)
+		}
+	})
+}
+
+// harResponseContent is the body decompressed, as text when it is text and
+// base64 encoded otherwise
+func harResponseContent(received []byte, header http.Header) harContent {
+	content := harContent{MimeType: header.Get("Content-Type")}
+
+	// the transport already decompressed the body if it asked for it itself
+	body := received
+	contentEncoding := header.Get("Content-Encoding")
+	if len(contentEncodings(contentEncoding)) > 0 && len(received) > 0 {
+		decompressed, err := Decompress(received, contentEncoding)
+		if err == nil {
+			body = decompressed
+			content.Compression = len(decompressed) - len(received)
+		}
+	}
+
+	content.Size = len(body)
+
+	mediaType, _, _ := mime.ParseMediaType(content.MimeType)
+	if utf8.Valid(body) && !IsBinary(body, mediaType) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+
+	return content
+}
@@ -0,0 +1,214 @@
+package src
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// how headers are printed, set from the flags with SetHeaderDisplay
+var headerOrder = "sorted"
+var includeHeaders []string
+var excludeHeaders []string
+
+// SetHeaderDisplay sets the order headers are printed in, "sorted" or "wire",
+// and the glob patterns used to pick which ones are printed
+func SetHeaderDisplay(order string, include []string, exclude []string) error {
+	if order != "sorted" && order != "wire" {
+		return fmt.Errorf("-header-order must be \"sorted\" or \"wire\", got: %s", order)
+	}
+
+	for _, pattern := range append(include, exclude...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid header pattern %q: %w", pattern, err)
+		}
+	}
+
+	headerOrder = order
+	includeHeaders = include
+	excludeHeaders = exclude
+
+	return nil
+}
+
+// splitHeaderPatterns turns a comma separated flag value into patterns
+func splitHeaderPatterns(patterns string) []string {
+	split := []string{}
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			split = append(split, pattern)
+		}
+	}
+
+	return split
+}
+
+// matchesHeaderPattern compares header names case insensitively
+func matchesHeaderPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isHeaderShown(name string) bool {
+	if len(includeHeaders) > 0 && !matchesHeaderPattern(name, includeHeaders) {
+		return false
+	}
+
+	return !matchesHeaderPattern(name, excludeHeaders)
+}
+
+// orderHeaderNames sorts the names of headers, with a wire order the names
+// seen on the wire come first in that order and anything else is sorted after
+func orderHeaderNames(headers http.Header, wireOrder []string) []string {
+	names := []string{}
+	seen := make(map[string]void)
+
+	for _, name := range wireOrder {
+		if _, exists := headers[name]; exists {
+			names = append(names, name)
+			seen[name] = member
+		}
+	}
+
+	remaining := []string{}
+	for name := range headers {
+		if _, exists := seen[name]; !exists {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Strings(remaining)
+
+	return append(names, remaining...)
+}
+
+// header order of the last response read, recorded by headerOrderConn
+var wireHeaderOrder headerOrderRecorder
+
+// ResponseHeaderOrder is the order the headers of the last response were
+// received in, nil unless -header-order is "wire"
+func ResponseHeaderOrder() []string {
+	return wireHeaderOrder.Order()
+}
+
+const (
+	recordStatusLine = iota
+	recordHeaders
+	recordBody
+)
+
+// headerOrderRecorder picks header names out of the bytes read from an
+// HTTP/1.x connection, the headers of 1xx responses are skipped
+type headerOrderRecorder struct {
+	mu      sync.Mutex
+	state   int
+	line    []byte
+	isInfo  bool
+	current []string
+	order   []string
+}
+
+func (r *headerOrderRecorder) Order() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.order
+}
+
+// requestSent starts looking for the next response
+func (r *headerOrderRecorder) requestSent() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == recordBody {
+		r.state = recordStatusLine
+		r.line = nil
+	}
+}
+
+func (r *headerOrderRecorder) read(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range p {
+		if r.state == recordBody {
+			return
+		}
+
+		if b != '\n' {
+			r.line = append(r.line, b)
+			continue
+		}
+
+		line := strings.TrimSuffix(string(r.line), "\r")
+		r.line = nil
+
+		switch {
+		case r.state == recordStatusLine:
+			_, statusCode, _ := strings.Cut(line, " ")
+			r.isInfo = strings.HasPrefix(statusCode, "1")
+			r.current = []string{}
+			r.state = recordHeaders
+
+		case line == "":
+			if r.isInfo {
+				r.state = recordStatusLine
+				continue
+			}
+			r.order = r.current
+			r.state = recordBody
+
+		case line[0] == ' ' || line[0] == '\t':
+			// folded continuation of the previous header
+
+		default:
+			name, _, found := strings.Cut(line, ":")
+			if !found {
+				continue
+			}
+
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if !containsString(r.current, name) {
+				r.current = append(r.current, name)
+			}
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// headerOrderConn feeds what is read from a connection to the recorder
+type headerOrderConn struct {
+	net.Conn
+	recorder *headerOrderRecorder
+}
+
+func (c *headerOrderConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.recorder.read(p[:n])
+	return n, err
+}
+
+func (c *headerOrderConn) Write(p []byte) (int, error) {
+	c.recorder.requestSent()
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,244 @@
+package src
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/fatih/color"
+)
+
+// chroma style and formatter used to highlight bodies, set from hurl.json
+var highlightStyle = ""
+var highlightFormatter = "terminal"
+
+// media types whose lexer can't be found from chroma's own mime types
+var mediaTypeLexers = map[string]string{
+	"application/json":       "json",
+	"text/json":              "json",
+	"text/html":              "html",
+	"application/xhtml+xml":  "html",
+	"application/xml":        "xml",
+	"text/xml":               "xml",
+	"application/yaml":       "yaml",
+	"application/x-yaml":     "yaml",
+	"text/yaml":              "yaml",
+	"text/x-yaml":            "yaml",
+	"application/javascript": "javascript",
+	"text/javascript":        "javascript",
+	"application/ecmascript": "javascript",
+	"text/css":               "css",
+	"application/toml":       "toml",
+	"application/graphql":    "graphql",
+}
+
+// structured syntax suffixes from RFC 6839, e.g. application/problem+json
+var structuredSuffixLexers = map[string]string{
+	"+json": "json",
+	"+xml":  "xml",
+	"+yaml": "yaml",
+}
+
+var genericMediaTypes = map[string]void{
+	"":                         member,
+	"text/plain":               member,
+	"application/octet-stream": member,
+}
+
+// SetHighlightStyle sets the chroma style and formatter used by FormatBody
+func SetHighlightStyle(style string, formatter string) error {
+	if style != "" {
+		if _, exists := styles.Registry[style]; !exists {
+			return fmt.Errorf("unknown highlight style: %s", style)
+		}
+		highlightStyle = style
+	}
+
+	if formatter != "" {
+		if _, exists := formatters.Registry[formatter]; !exists || !strings.HasPrefix(formatter, "terminal") {
+			return fmt.Errorf("unknown highlight formatter, expected terminal, terminal8, terminal16, terminal256 or terminal16m: %s", formatter)
+		}
+		highlightFormatter = formatter
+	}
+
+	return nil
+}
+
+// lexerForMediaType finds the chroma lexer for a media type, returns "" when
+// there is none so the lexer gets guessed from the content
+func lexerForMediaType(mediaType string) string {
+	if lexer, exists := mediaTypeLexers[mediaType]; exists {
+		return lexer
+	}
+
+	for suffix, lexer := range structuredSuffixLexers {
+		if strings.HasSuffix(mediaType, suffix) {
+			return lexer
+		}
+	}
+
+	// chroma claims some generic types for specific languages, e.g. text/plain
+	// for systemd units
+	if _, generic := genericMediaTypes[mediaType]; generic {
+		return ""
+	}
+
+	lexer := lexers.MatchMimeType(mediaType)
+	if lexer != nil {
+		return lexer.Config().Name
+	}
+
+	return ""
+}
+
+// PrettifyXml indents an XML document, the raw tokens are used so namespace
+// prefixes are kept as they were written
+func PrettifyXml(x []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(x))
+	decoder.Strict = false
+
+	buffer := bytes.Buffer{}
+	encoder := xml.NewEncoder(&buffer)
+	encoder.Indent("", "  ")
+
+	depth := 0
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return []byte{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			t.Name = prefixedXmlName(t.Name)
+			for i, attr := range t.Attr {
+				t.Attr[i].Name = prefixedXmlName(attr.Name)
+			}
+			token = t
+			depth++
+		case xml.EndElement:
+			t.Name = prefixedXmlName(t.Name)
+			token = t
+			depth--
+		case xml.CharData:
+			// whitespace between elements is replaced by the indentation
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		}
+
+		err = encoder.EncodeToken(token)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		// the encoder doesn't indent after prologue tokens like <?xml ...?>
+		switch token.(type) {
+		case xml.ProcInst, xml.Directive, xml.Comment:
+			if depth == 0 {
+				err = encoder.Flush()
+				if err != nil {
+					return []byte{}, err
+				}
+				buffer.WriteString("\n")
+			}
+		}
+	}
+
+	err := encoder.Flush()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func prefixedXmlName(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+
+	return xml.Name{Local: fmt.Sprintf("%s:%s", name.Space, name.Local)}
+}
+
+// FormatFormUrlEncoded shows each field of a form body decoded on its own line
+func FormatFormUrlEncoded(body []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	for _, pair := range strings.Split(strings.TrimSpace(string(body)), "&") {
+		if pair == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(pair, "=")
+
+		decodedKey, err := url.QueryUnescape(key)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		decodedValue, err := url.QueryUnescape(value)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		buffer.WriteString(fmt.Sprintf("%s = %s\n", yellow(decodedKey), decodedValue))
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// FormatCsv colours each column of a CSV body differently so the columns can
+// be followed across rows
+func FormatCsv(body []byte) ([]byte, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	columnColors := []*color.Color{
+		color.New(color.FgCyan),
+		color.New(color.FgYellow),
+		color.New(color.FgGreen),
+		color.New(color.FgMagenta),
+		color.New(color.FgBlue),
+		color.New(color.FgRed),
+	}
+
+	buffer := bytes.Buffer{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return []byte{}, err
+		}
+
+		for i, field := range record {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+
+			if strings.ContainsAny(field, ",\"\r\n") {
+				field = fmt.Sprintf("\"%s\"", strings.ReplaceAll(field, "\"", "\"\""))
+			}
+
+			buffer.WriteString(columnColors[i%len(columnColors)].Sprint(field))
+		}
+		buffer.WriteString("\n")
+	}
+
+	return buffer.Bytes(), nil
+}
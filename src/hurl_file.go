@@ -62,7 +62,7 @@ func validateTemplateVariable(v []byte) error {
 }
 
 func isValidMethod(m string) bool {
-	return m == "GET" || m == "POST" || m == "PUT" || m == "PATCH" || m == "DELETE"
+	return m == "GET" || m == "POST" || m == "PUT" || m == "PATCH" || m == "DELETE" || m == "WS" || m == "GRPC"
 }
 
 func extractFileEmbedPath(s string) string {
@@ -136,35 +136,47 @@ func parseKeyValPair(s string) (string, string, error) {
 	return key, value, nil
 }
 
-func parseMultiPart(sc *bufio.Scanner) ([]MultiPartItem, error) {
-	multipartItems := []MultiPartItem{}
+// parseMultiPartLine reads a `form-data; name="field"; value="value"` line,
+// with filename in place of value for files
+func parseMultiPartLine(line string) (MultiPartItem, error) {
+	multipartComponents := strings.Split(line, ";")
+	if len(multipartComponents) < 3 {
+		return MultiPartItem{}, fmt.Errorf("expected form-data; name=\"[name]\"; value=\"[value]\", found \"%s\"", line)
+	}
 
-	for sc.Scan() {
-		multipartComponents := strings.Split(sc.Text(), ";")
+	multipartTag := strings.TrimSpace(multipartComponents[MULTIPART_FORM_TAG])
+	name := strings.TrimSpace(multipartComponents[MULTIPART_NAME])
+	value := strings.TrimSpace(multipartComponents[MULTIPART_VALUE])
 
-		multipartTag := strings.TrimSpace(multipartComponents[MULTIPART_FORM_TAG])
-		name := strings.TrimSpace(multipartComponents[MULTIPART_NAME])
-		value := strings.TrimSpace(multipartComponents[MULTIPART_VALUE])
+	if multipartTag != "form-data" {
+		return MultiPartItem{}, fmt.Errorf("found \"%s\" instead of \"form-data\" multipart tag", multipartTag)
+	}
 
-		if multipartTag != "form-data" {
-			return []MultiPartItem{}, fmt.Errorf("found \"%s\" instead of \"form-data\" multipart tag", multipartTag)
-		}
+	formFieldNameKey, formFieldName, err := parseKeyValPair(name)
+	if err != nil {
+		return MultiPartItem{}, err
+	}
+	if formFieldNameKey != "name" {
+		return MultiPartItem{}, fmt.Errorf("expected to find \"name\" in form data, found \"%s\"", formFieldNameKey)
+	}
 
-		formFieldNameKey, formFieldName, err := parseKeyValPair(name)
-		if err != nil {
-			return []MultiPartItem{}, err
-		}
-		if formFieldNameKey != "name" {
-			return []MultiPartItem{}, fmt.Errorf("expected to find \"name\" in form data, found \"%s\"", formFieldNameKey)
-		}
+	formFieldValueKey, formFieldValue, err := parseKeyValPair(value)
+	if err != nil {
+		return MultiPartItem{}, err
+	}
+
+	return MultiPartItem{formFieldName, formFieldValueKey == "filename", formFieldValue}, nil
+}
 
-		formFieldValueKey, formFieldValue, err := parseKeyValPair(value)
+func parseMultiPart(sc *bufio.Scanner) ([]MultiPartItem, error) {
+	multipartItems := []MultiPartItem{}
+
+	for sc.Scan() {
+		multipartItem, err := parseMultiPartLine(sc.Text())
 		if err != nil {
 			return []MultiPartItem{}, err
 		}
 
-		multipartItem := MultiPartItem{formFieldName, formFieldValueKey == "filename", formFieldValue}
-
 		multipartItems = append(multipartItems, multipartItem)
 	}
 
@@ -205,6 +217,35 @@ func parseBody(sc *bufio.Scanner) ([]byte, bool, error) {
 	return bodyBuffer.Bytes(), false, nil
 }
 
+// parseWebSocketMessages reads the messages to send over a websocket, each
+// message is separated by a blank line so JSON can span multiple lines
+func parseWebSocketMessages(sc *bufio.Scanner) ([][]byte, error) {
+	messages := [][]byte{}
+	message := bytes.Buffer{}
+
+	for sc.Scan() {
+		if strings.TrimSpace(sc.Text()) == "" {
+			if message.Len() > 0 {
+				messages = append(messages, bytes.Clone(bytes.TrimSuffix(message.Bytes(), []byte{'\n'})))
+				message.Reset()
+			}
+			continue
+		}
+
+		message.Write(sc.Bytes())
+		message.Write([]byte{'\n'})
+	}
+	if sc.Err() != nil {
+		return [][]byte{}, sc.Err()
+	}
+
+	if message.Len() > 0 {
+		messages = append(messages, bytes.TrimSuffix(message.Bytes(), []byte{'\n'}))
+	}
+
+	return messages, nil
+}
+
 type MultiPartItem struct {
 	Name       string
 	IsFilePath bool
@@ -219,6 +260,8 @@ type HurlFile struct {
 	FileEmbed         string
 	MultipartFormData []MultiPartItem
 	MultipartBoundary string
+	WebSocketMessages [][]byte
+	IsGraphQL         bool
 
 	// CLI and hurl.json options
 	Config HurlConfig
@@ -289,13 +332,13 @@ func ParseHurlFile(r io.Reader) (*HurlFile, error) {
 
 	h.Headers = headerMap
 
-	hostHeaderVal, exists := h.Headers["Host"]
+	hostHeaderVal, exists := h.Headers[h.headerName("Host")]
 	if exists && h.URL.Hostname() != hostHeaderVal {
 		PrintWarning(errors.New("host header value does not match host in URL, using host in URL"))
-		h.Headers["Host"] = h.URL.Hostname()
+		h.Headers[h.headerName("Host")] = h.URL.Hostname()
 	}
 
-	h.Headers["User-Agent"] = "hurl/0.1.0"
+	h.Headers[h.headerName("User-Agent")] = "hurl/0.1.0"
 
 	// no body, done
 	if !scanFoundToken {
@@ -304,8 +347,58 @@ func ParseHurlFile(r io.Reader) (*HurlFile, error) {
 
 	//=== body ===//
 
+	if h.IsWebSocket() {
+		messages, err := parseWebSocketMessages(sc)
+		if err != nil {
+			return &HurlFile{}, err
+		}
+
+		h.WebSocketMessages = messages
+		return h, nil
+	}
+
+	// gRPC bodies are JSON that gets encoded with the method's descriptor
+	if h.IsGRPC() {
+		body, _, err := parseBody(sc)
+		if err != nil {
+			return &HurlFile{}, err
+		}
+
+		h.Body = body
+		return h, nil
+	}
+
+	// the rest of the body is read ahead to see if it's a GraphQL query, then
+	// scanned again with every line ending in a newline so other bodies are
+	// read exactly as they would have been
+	bodyLines := []string{}
+	rest := bytes.Buffer{}
+	for sc.Scan() {
+		bodyLines = append(bodyLines, sc.Text())
+		rest.Write(sc.Bytes())
+		rest.WriteByte('\n')
+	}
+	if sc.Err() != nil {
+		return &HurlFile{}, sc.Err()
+	}
+	sc = bufio.NewScanner(&rest)
+
+	if isGraphQLBody(bodyLines) {
+		graphQLRequest, err := parseGraphQL(sc)
+		if err != nil {
+			return &HurlFile{}, err
+		}
+
+		err = h.setGraphQLRequest(graphQLRequest)
+		if err != nil {
+			return &HurlFile{}, err
+		}
+
+		return h, nil
+	}
+
 	// TODO: check valid content-type
-	headerContentType, exists := h.Headers["Content-Type"]
+	headerContentType, exists := h.Headers[h.headerName("Content-Type")]
 	if exists && headerContentType == "multipart/form-data" {
 		// form data
 		multipartFormData, err := parseMultiPart(sc)
@@ -315,7 +408,7 @@ func ParseHurlFile(r io.Reader) (*HurlFile, error) {
 
 		h.MultipartFormData = multipartFormData
 
-	} else if exists && headerContentType != "multipart/form-data" {
+	} else if exists {
 		// read body as is, might have file embed
 		body, containsFileEmbed, err := parseBody(sc)
 		if err != nil {
@@ -342,30 +435,73 @@ func ParseHurlFile(r io.Reader) (*HurlFile, error) {
 	return h, nil
 }
 
+func sniffContentType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// http.DetectContentType only considers the first 512 bytes
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(f, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(sniff[:n]), nil
+}
+
+func createMultipartFilePart(writer *multipart.Writer, multiPartItem MultiPartItem) (io.Writer, os.FileInfo, error) {
+	file, err := os.Stat(multiPartItem.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contentType, err := sniffContentType(multiPartItem.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mimeHeader := make(textproto.MIMEHeader)
+
+	mimeHeader.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"; filename=\"%s\"", multiPartItem.Name, file.Name()))
+	mimeHeader.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(mimeHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return part, file, nil
+}
+
+// IsWebSocket reports whether the request file describes a websocket session
+// rather than a regular HTTP request
+func (h *HurlFile) IsWebSocket() bool {
+	return h.Method == "WS" || h.URL.Scheme == "ws" || h.URL.Scheme == "wss"
+}
+
+// WriteMultipart streams the multipart form data to writer, copying file parts
+// straight from disk instead of loading them into memory
 func WriteMultipart(h HurlFile, writer *multipart.Writer) error {
 	for _, multiPartItem := range h.MultipartFormData {
 		if multiPartItem.IsFilePath {
-			file, err := os.Stat(multiPartItem.Value)
+			part, _, err := createMultipartFilePart(writer, multiPartItem)
 			if err != nil {
-				return nil
+				return err
 			}
 
-			fileData, err := os.ReadFile(multiPartItem.Value)
+			file, err := os.Open(multiPartItem.Value)
 			if err != nil {
-				return nil
+				return err
 			}
 
-			mimeHeader := make(textproto.MIMEHeader)
-
-			mimeHeader.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"; filename=\"%s\"", multiPartItem.Name, file.Name()))
-			mimeHeader.Set("Content-Type", http.DetectContentType(fileData))
-
-			part, err := writer.CreatePart(mimeHeader)
+			_, err = io.Copy(part, file)
+			file.Close()
 			if err != nil {
 				return err
 			}
-
-			part.Write(fileData)
 		} else {
 			err := writer.WriteField(multiPartItem.Name, multiPartItem.Value)
 			if err != nil {
@@ -374,43 +510,143 @@ func WriteMultipart(h HurlFile, writer *multipart.Writer) error {
 		}
 	}
 
-	writer.Close()
-	return nil
+	return writer.Close()
+}
+
+// multipartContentLength computes the size of the encoded multipart body
+// without reading any files, returns -1 if the size can't be known up front
+func multipartContentLength(h HurlFile, boundary string) int64 {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return -1
+	}
+
+	for _, multiPartItem := range h.MultipartFormData {
+		if multiPartItem.IsFilePath {
+			_, file, err := createMultipartFilePart(writer, multiPartItem)
+			if err != nil || !file.Mode().IsRegular() {
+				return -1
+			}
+
+			counter.n += file.Size()
+		} else {
+			err := writer.WriteField(multiPartItem.Name, multiPartItem.Value)
+			if err != nil {
+				return -1
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return -1
+	}
+
+	return counter.n
+}
+
+// headerName is the name a header was written with in the request file,
+// header names are case insensitive so content-type is Content-Type. The name
+// itself is returned when the file doesn't set the header
+func (h *HurlFile) headerName(name string) string {
+	for written := range h.Headers {
+		if strings.EqualFold(written, name) {
+			return written
+		}
+	}
+
+	return name
 }
 
 func (h *HurlFile) NewRequest() (*http.Request, error) {
-	body := &bytes.Buffer{}
+	var body io.Reader
+	contentLength := int64(0)
+
+	// getBody sends the body again when a 307 or 308 redirect asks for it,
+	// nil when it can only be read once like a pipe
+	var getBody func() (io.ReadCloser, error)
 
-	contentType, exists := h.Headers["Content-Type"]
+	contentType, exists := h.Headers[h.headerName("Content-Type")]
 	if !exists && h.Method != "GET" && h.Method != "DELETE" {
 		return &http.Request{}, errors.New("\"Content-Type\" header missing")
 	}
 
 	if contentType == "multipart/form-data" && len(h.MultipartFormData) > 0 {
-		writer := multipart.NewWriter(body)
-		h.Headers["Content-Type"] = fmt.Sprintf("%s; boundary=%s", contentType, writer.Boundary())
-		h.MultipartBoundary = writer.Boundary()
+		boundary := multipart.NewWriter(io.Discard).Boundary()
+		h.Headers[h.headerName("Content-Type")] = fmt.Sprintf("%s; boundary=%s", contentType, boundary)
+		h.MultipartBoundary = boundary
+
+		contentLength = multipartContentLength(*h, boundary)
+
+		hurlFile := *h
+		newBody := func() (io.ReadCloser, error) {
+			pr, pw := io.Pipe()
+			writer := multipart.NewWriter(pw)
+			writer.SetBoundary(boundary)
+			go func() {
+				pw.CloseWithError(WriteMultipart(hurlFile, writer))
+			}()
+
+			return pr, nil
+		}
 
-		WriteMultipart(*h, writer)
+		// the parts are only read again when they're all regular files
+		if contentLength >= 0 {
+			getBody = newBody
+		}
+		body, _ = newBody()
 	} else if h.FileEmbed != "" {
-		fileData, err := os.ReadFile(h.FileEmbed)
+		file, err := os.Open(h.FileEmbed)
 		if err != nil {
-			return &http.Request{}, nil
+			return &http.Request{}, err
+		}
+
+		fileInfo, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return &http.Request{}, err
+		}
+
+		// pipes, devices etc. have no known size, send them chunked
+		contentLength = -1
+		if fileInfo.Mode().IsRegular() {
+			contentLength = fileInfo.Size()
+
+			path := h.FileEmbed
+			getBody = func() (io.ReadCloser, error) {
+				return os.Open(path)
+			}
 		}
-		body.Write(fileData)
+
+		body = file
 	} else {
-		body.Write(h.Body)
+		body = bytes.NewReader(h.Body)
+		contentLength = int64(len(h.Body))
+
+		content := h.Body
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		}
 	}
 
-	req, err := http.NewRequest(h.Method, h.URL.String(), body)
+	req, err := http.NewRequest(h.Method, h.URL.String(), NewProgressReader(body, contentLength))
 	if err != nil {
 		return nil, err
 	}
 
+	// http.NewRequest can only infer the length for in memory readers
+	req.ContentLength = contentLength
+	req.GetBody = getBody
+	if contentLength == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+	}
+
 	header := http.Header{}
 	for name, val := range h.Headers {
 		header[name] = []string{val}
 	}
+	setAcceptEncoding(header)
 	req.Header = header
 
 	return req, nil
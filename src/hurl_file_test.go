@@ -1,5 +1,19 @@
 package src
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
 // import (
 // 	"fmt"
 // 	"net/url"
@@ -133,3 +147,214 @@ package src
 // 	assert.Equal(t, headers, hurlFile.Headers)
 // 	assert.Equal(t, []string{"path/idk.png"}, hurlFile.FilePaths)
 // }
+
+func TestParseHurlFileBody(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		body string
+	}{
+		{"text", "POST https://example.com\nContent-Type: text/plain\n\nhello\n", "hello\n"},
+		{"no final newline", "POST https://example.com\nContent-Type: text/plain\n\nhello", "hello\n"},
+		{"trailing blank lines", "POST https://example.com\nContent-Type: text/plain\n\na\n\n", "a\n\n"},
+		{"blank lines inside", "POST https://example.com\nContent-Type: text/plain\n\n\na\n\n\nb\n", "\na\n\n\nb\n"},
+		{"json", "POST https://example.com\nContent-Type: application/json\n\n{\n  \"a\": 1\n}\n\n", "{\n  \"a\": 1\n}\n\n"},
+		{"form", "POST https://example.com\nContent-Type: application/x-www-form-urlencoded\n\na=1&b=2\n", "a=1&b=2\n"},
+		{"crlf", "POST https://example.com\r\nContent-Type: text/plain\r\n\r\na\r\nb\r\n", "a\nb\n"},
+		{"graphql tag later in the body", "POST https://example.com\nContent-Type: text/plain\n\nquery\nGRAPHQL\n", "query\nGRAPHQL\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hurlFile, err := ParseHurlFile(strings.NewReader(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(hurlFile.Body) != test.body {
+				t.Errorf("expected body %q, got %q", test.body, hurlFile.Body)
+			}
+			if hurlFile.IsGraphQL {
+				t.Errorf("expected a plain body, not a GraphQL query")
+			}
+		})
+	}
+}
+
+// newTestRequest builds the request for a request file the way hurl does
+func newTestRequest(t *testing.T, src string) *http.Request {
+	t.Helper()
+
+	hurlFile, err := ParseHurlFile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := hurlFile.NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return req
+}
+
+// checkRequestBody checks the body is ContentLength bytes long and that
+// GetBody returns it again, from the start, as many times as it's asked
+func checkRequestBody(t *testing.T, req *http.Request) []byte {
+	t.Helper()
+
+	sent, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int64(len(sent)) != req.ContentLength {
+		t.Errorf("ContentLength is %d but %d bytes were sent", req.ContentLength, len(sent))
+	}
+
+	if req.GetBody == nil {
+		t.Fatal("expected GetBody to be set")
+	}
+	for i := 0; i < 2; i++ {
+		body, err := req.GetBody()
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(again, sent) {
+			t.Errorf("GetBody returned\n%q\ninstead of\n%q", again, sent)
+		}
+	}
+
+	return sent
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestNewRequestBody(t *testing.T) {
+	req := newTestRequest(t, "POST https://example.com\nContent-Type: application/json\n\n{\"a\": 1}\n")
+
+	sent := checkRequestBody(t, req)
+	if string(sent) != "{\"a\": 1}\n" {
+		t.Errorf("unexpected body %q", sent)
+	}
+}
+
+func TestNewRequestNoBody(t *testing.T) {
+	req := newTestRequest(t, "GET https://example.com\n")
+
+	if req.ContentLength != 0 || req.Body != http.NoBody {
+		t.Errorf("expected no body, got ContentLength %d", req.ContentLength)
+	}
+	checkRequestBody(t, req)
+}
+
+func TestNewRequestFileEmbed(t *testing.T) {
+	content := strings.Repeat("0123456789", 10000)
+	path := writeTestFile(t, "payload.txt", content)
+
+	req := newTestRequest(t, fmt.Sprintf("POST https://example.com\nContent-Type: text/plain\n\n@file=%s\n", path))
+
+	sent := checkRequestBody(t, req)
+	if string(sent) != content {
+		t.Errorf("expected the file to be sent as it is, got %d bytes", len(sent))
+	}
+}
+
+func TestNewRequestMultipart(t *testing.T) {
+	content := strings.Repeat("multipart ", 5000)
+	path := writeTestFile(t, "upload.txt", content)
+
+	src := fmt.Sprintf("POST https://example.com\nContent-Type: multipart/form-data\n\nform-data; name=\"id\"; value=\"42\"\nform-data; name=\"file\"; filename=\"%s\"\n", path)
+	req := newTestRequest(t, src)
+
+	sent := checkRequestBody(t, req)
+
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(sent), params["boundary"])
+	parts := map[string]string{}
+	fileNames := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		value, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[part.FormName()] = string(value)
+		fileNames[part.FormName()] = part.FileName()
+	}
+
+	if parts["id"] != "42" || parts["file"] != content || fileNames["file"] != "upload.txt" {
+		t.Errorf("unexpected parts, id %q and a %d byte file named %q", parts["id"], len(parts["file"]), fileNames["file"])
+	}
+}
+
+// a 307 redirect sends the body again, which needs GetBody for streamed bodies
+func TestNewRequestRedirectResendsBody(t *testing.T) {
+	content := strings.Repeat("redirected ", 1000)
+	path := writeTestFile(t, "payload.txt", content)
+
+	received := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if int64(len(body)) != r.ContentLength {
+			t.Errorf("Content-Length %d but %d bytes received", r.ContentLength, len(body))
+		}
+		received = append(received, body)
+
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+
+	for _, body := range []string{
+		fmt.Sprintf("@file=%s", path),
+		fmt.Sprintf("form-data; name=\"file\"; filename=\"%s\"", path),
+	} {
+		contentType := "text/plain"
+		if strings.HasPrefix(body, "form-data") {
+			contentType = "multipart/form-data"
+		}
+
+		received = nil
+		req := newTestRequest(t, fmt.Sprintf("POST %s/old\nContent-Type: %s\n\n%s\n", server.URL, contentType, body))
+
+		res, err := NewHttpClient(HurlConfig{}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if len(received) != 2 || !bytes.Equal(received[0], received[1]) || !bytes.Contains(received[1], []byte(content)) {
+			t.Errorf("expected the %s body to be sent again after the redirect", contentType)
+		}
+	}
+}
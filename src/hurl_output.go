@@ -14,14 +14,23 @@ var LOADING_CHARS = [...]string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦",
 
 type HurlOutput struct {
 	Config HurlConfig
+
+	// bytes already on disk when resuming a download with -continue
+	ResumeOffset int64
+
+	// the request was built from a GraphQL body
+	GraphQL bool
+
+	// request file the response snapshot is kept next to
+	HurlFilePath string
 }
 
-func WaitForHttpRequest(req *http.Request) (*http.Response, error) {
+func WaitForHttpRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 	errCh := make(chan error)
 	resCh := make(chan *http.Response)
 
 	go func() {
-		res, err := http.DefaultClient.Do(req)
+		res, err := client.Do(req)
 		if err != nil {
 			errCh <- err
 			return
@@ -30,29 +39,53 @@ func WaitForHttpRequest(req *http.Request) (*http.Response, error) {
 		resCh <- res
 	}()
 
+	progress, _ := req.Body.(*ProgressReader)
+
+	// the spinner is only for people watching, keep it out of pipes and files
+	showSpinner := isTerminal(os.Stderr)
+
+	// the response is handled as soon as it arrives rather than on the next
+	// spinner tick, so its body is read right away
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
 	i := 0
+	if showSpinner {
+		PrintSpinner(i, progress)
+	}
 	for {
 		select {
 		case res := <-resCh:
-			ClearSpinner()
+			if showSpinner {
+				ClearSpinner()
+			}
 			return res, nil
 		case err := <-errCh:
-			ClearSpinner()
+			if showSpinner {
+				ClearSpinner()
+			}
 			return nil, err
-		default:
-			PrintSpinner(i)
-			time.Sleep(50 * time.Millisecond)
+		case <-ticker.C:
+			i = (i + 1) % len(LOADING_CHARS)
+			if showSpinner {
+				PrintSpinner(i, progress)
+			}
 		}
-		i = (i + 1) % len(LOADING_CHARS)
 	}
 }
 
-func PrintSpinner(i int) {
-	fmt.Printf("=== sending %s ===\r", LOADING_CHARS[i])
+func PrintSpinner(i int, progress *ProgressReader) {
+	if progress != nil && progress.BytesRead() > 0 && progress.Total != 0 {
+		fmt.Fprintf(os.Stderr, "=== sending %s %s ===\r", LOADING_CHARS[i], progress)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "=== sending %s ===\r", LOADING_CHARS[i])
 }
 
 func ClearSpinner() {
-	fmt.Print("\r")
+	// carriage return then erase to the end of the line
+	fmt.Fprint(os.Stderr, "\r\033[K")
 }
 
 func (h HurlOutput) OutputRequest(hurlFile *HurlFile, req http.Request) error {
@@ -64,7 +97,7 @@ func (h HurlOutput) OutputRequest(hurlFile *HurlFile, req http.Request) error {
 	// add this in manually since not set in http.Request for some reason
 	req.Header.Set("Host", req.Host)
 
-	headers := FormatHeaders(req.Header, ">")
+	headers := FormatHeaders(req.Header, ">", nil)
 	buffer.Write(headers)
 
 	// separate body with newline
@@ -114,44 +147,133 @@ func (h HurlOutput) OutputResponse(res http.Response) error {
 	statusLine := FormatStatusLine(res)
 	buffer.Write([]byte(statusLine))
 
-	headers := FormatHeaders(res.Header, "<")
+	headers := FormatHeaders(res.Header, "<", ResponseHeaderOrder())
 	buffer.Write([]byte(headers))
 
+	if h.Config.HeadersOnly {
+		fmt.Print(buffer.String())
+		return nil
+	}
+
 	// separate body with newline
 	buffer.Write([]byte("\n"))
 
+	// resumed downloads are streamed straight to disk, the body is never
+	// prettified since it may only be part of the file
+	bodyOutputPath := h.Config.BodyOutputPath
+	if len(bodyOutputPath) > 0 && h.Config.Continue {
+		err := WriteResumableBody(res, bodyOutputPath, h.ResumeOffset)
+		if err != nil {
+			return err
+		}
+
+		return h.outputBodyFilePath(&buffer, bodyOutputPath)
+	}
+
+	// a missing or malformed Content-Type is left to sniffing
 	contentType := res.Header.Get("Content-Type")
-	mediaType, _, err := mime.ParseMediaType(contentType)
+	mediaType, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return nil
+		mediaType = ""
 	}
 
-	bodyBytes, err := io.ReadAll(res.Body)
+	receivedBytes, err := io.ReadAll(res.Body)
 	if err != nil {
 		return err
 	}
 
-	bodyOutputPath := h.Config.BodyOutputPath
+	if h.Config.RawBody {
+		return h.outputRawBody(&buffer, res, receivedBytes, mediaType)
+	}
+
+	rawBodyBytes := receivedBytes
+	contentEncoding := res.Header.Get("Content-Encoding")
+	if len(contentEncodings(contentEncoding)) > 0 && len(receivedBytes) > 0 {
+		rawBodyBytes, err = Decompress(receivedBytes, contentEncoding)
+		if err != nil {
+			return err
+		}
+
+		buffer.WriteString(FormatCompressionTitle(contentEncoding, len(receivedBytes), len(rawBodyBytes)))
+	}
+
+	// text bodies are shown as UTF-8 but files written with -o keep the bytes
+	// as they were sent. Binary bodies are never decoded so their summary is of
+	// the bytes the server sent
+	binary := IsBinary(rawBodyBytes, mediaType)
+	bodyBytes := rawBodyBytes
+	if !binary {
+		bodyBytes = DecodeCharset(rawBodyBytes, params["charset"])
+	}
+
+	if h.Config.Query != "" {
+		return h.outputQueryResults(&buffer, bodyBytes)
+	}
+
+	if h.Config.Diff {
+		return h.outputSnapshotDiff(&buffer, res, bodyBytes, mediaType)
+	}
+
+	if h.Config.Snapshot {
+		err := h.saveSnapshot(res, bodyBytes, mediaType)
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.Config.RemoteName {
+		bodyOutputPath = RemoteName(res)
+	}
+
 	if len(bodyOutputPath) > 0 {
-		if mediaType == "application/json" {
-			prettified, err := PrettifyJson(bodyBytes)
+		// a checksum is of the file as the server sent it
+		fileBytes := rawBodyBytes
+		if mediaType == "application/json" && h.Config.Checksum == "" {
+			prettified, err := PrettifyJson(rawBodyBytes)
 			if err != nil {
 				return err
 			}
 
-			bodyBytes = prettified
+			fileBytes = prettified
+		}
+
+		err := os.WriteFile(bodyOutputPath, fileBytes, 0644)
+		if err != nil {
+			return err
 		}
 
-		err := os.WriteFile(bodyOutputPath, bodyBytes, 0644)
+		err = h.outputBodyFilePath(&buffer, bodyOutputPath)
 		if err != nil {
 			return err
 		}
 
-		title := FormatFilePathsTitle()
-		buffer.Write([]byte(title))
+		if h.GraphQL {
+			_, errCount, err := FormatGraphQLBody(bodyBytes)
+			if err != nil {
+				return err
+			}
+
+			return graphQLErrorsError(errCount)
+		}
+
+		return nil
+	}
+
+	if h.GraphQL && (mediaType == "application/json" || mediaType == "application/graphql-response+json") {
+		body, errCount, err := FormatGraphQLBody(bodyBytes)
+		if err != nil {
+			return err
+		}
+
+		buffer.Write(body)
+
+		fmt.Printf("%s\n", buffer.String())
+
+		return graphQLErrorsError(errCount)
+	}
 
-		filePaths := FormatFileEmbed(bodyOutputPath)
-		buffer.Write(filePaths)
+	if binary {
+		buffer.Write(FormatBinarySummary(bodyBytes, mediaType, h.Config.HexDumpLength))
 
 		fmt.Printf("%s\n", buffer.String())
 
@@ -169,3 +291,172 @@ func (h HurlOutput) OutputResponse(res http.Response) error {
 
 	return nil
 }
+
+// outputRawBody prints or writes the body exactly as it was received, still
+// compressed if it was sent compressed. Binary bodies are summarised rather
+// than printed to a terminal
+func (h HurlOutput) outputRawBody(buffer *bytes.Buffer, res http.Response, receivedBytes []byte, mediaType string) error {
+	bodyOutputPath := h.Config.BodyOutputPath
+	if h.Config.RemoteName {
+		bodyOutputPath = RemoteName(res)
+	}
+
+	if len(bodyOutputPath) > 0 {
+		err := os.WriteFile(bodyOutputPath, receivedBytes, 0644)
+		if err != nil {
+			return err
+		}
+
+		return h.outputBodyFilePath(buffer, bodyOutputPath)
+	}
+
+	if StdoutIsTerminal() && IsBinary(receivedBytes, "") {
+		PrintWarning(fmt.Errorf("not printing a binary body to the terminal, redirect stdout or use -o to save it"))
+		buffer.Write(FormatBinarySummary(receivedBytes, mediaType, h.Config.HexDumpLength))
+
+		fmt.Printf("%s\n", buffer.String())
+
+		return nil
+	}
+
+	fmt.Print(buffer.String())
+	os.Stdout.Write(receivedBytes)
+
+	return nil
+}
+
+// outputQueryResults prints the results of -q in place of the body, in raw
+// mode only the results are printed so they can be used from a shell
+func (h HurlOutput) outputQueryResults(buffer *bytes.Buffer, bodyBytes []byte) error {
+	results, err := QueryJson(bodyBytes, h.Config.Query)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := FormatQueryResults(results, IsJsonPath(h.Config.Query), h.Config.Raw)
+	if err != nil {
+		return err
+	}
+
+	if h.Config.Raw {
+		fmt.Print(string(formatted))
+		return nil
+	}
+
+	body, err := FormatBody(formatted, "application/json")
+	if err != nil {
+		return err
+	}
+
+	buffer.Write(body)
+
+	fmt.Printf("%s\n", buffer.String())
+
+	return nil
+}
+
+// saveSnapshot overwrites the snapshot of the request file with the response
+func (h HurlOutput) saveSnapshot(res http.Response, bodyBytes []byte, mediaType string) error {
+	snapshot, err := NewSnapshot(res, bodyBytes, mediaType, h.Config.SnapshotHeaders)
+	if err != nil {
+		return err
+	}
+
+	snapshotPath := SnapshotPath(h.HurlFilePath)
+
+	err = WriteSnapshot(snapshotPath, snapshot)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "snapshot saved to %s\n", snapshotPath)
+
+	return nil
+}
+
+// outputSnapshotDiff prints the differences from the saved snapshot in place
+// of the body and fails when there are any so it can be used in scripts
+func (h HurlOutput) outputSnapshotDiff(buffer *bytes.Buffer, res http.Response, bodyBytes []byte, mediaType string) error {
+	snapshot, err := NewSnapshot(res, bodyBytes, mediaType, h.Config.SnapshotHeaders)
+	if err != nil {
+		return err
+	}
+
+	changes, err := DiffSnapshot(SnapshotPath(h.HurlFilePath), snapshot, h.Config.SnapshotIgnore)
+	if err != nil {
+		return err
+	}
+
+	buffer.Write(FormatSnapshotDiff(changes))
+
+	fmt.Printf("%s\n", buffer.String())
+
+	if len(changes) > 0 {
+		return fmt.Errorf("response differs from snapshot in %d place(s)", len(changes))
+	}
+
+	return nil
+}
+
+func graphQLErrorsError(errCount int) error {
+	if errCount == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("GraphQL response contains %d error(s)", errCount)
+}
+
+func (h HurlOutput) outputBodyFilePath(buffer *bytes.Buffer, bodyOutputPath string) error {
+	if h.Config.Checksum != "" {
+		err := VerifyChecksum(bodyOutputPath, h.Config.Checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	title := FormatFilePathsTitle()
+	buffer.Write([]byte(title))
+
+	filePaths := FormatFileEmbed(bodyOutputPath)
+	buffer.Write(filePaths)
+
+	fmt.Printf("%s\n", buffer.String())
+
+	return nil
+}
+
+// OutputGRPCResponse prints every message of a gRPC response as JSON, followed
+// by the trailers holding the call's status
+func (h HurlOutput) OutputGRPCResponse(res http.Response, call *GRPCCall) error {
+	buffer := bytes.Buffer{}
+
+	statusLine := FormatStatusLine(res)
+	buffer.Write([]byte(statusLine))
+
+	headers := FormatHeaders(res.Header, "<", ResponseHeaderOrder())
+	buffer.Write([]byte(headers))
+
+	// separate body with newline
+	buffer.Write([]byte("\n"))
+
+	messages, decodeErr := call.DecodeResponse(&res)
+
+	for _, message := range messages {
+		body, err := FormatBody(message, "application/json")
+		if err != nil {
+			return err
+		}
+
+		buffer.Write(body)
+		buffer.Write([]byte("\n"))
+	}
+
+	if len(res.Trailer) > 0 {
+		trailers := FormatHeaders(res.Trailer, "<", nil)
+		buffer.Write([]byte(trailers))
+	}
+
+	fmt.Printf("%s\n", buffer.String())
+
+	return decodeErr
+}
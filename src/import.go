@@ -0,0 +1,189 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+type importedHeader struct {
+	Name  string
+	Value string
+}
+
+// importedRequest is a request read from another tool, to be written out as a
+// request file
+type importedRequest struct {
+	Method    string
+	URL       string
+	Headers   []importedHeader
+	Body      string
+	FileEmbed string
+	Multipart []MultiPartItem
+}
+
+func (r *importedRequest) header(name string) (string, bool) {
+	for _, header := range r.Headers {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func (r *importedRequest) setHeader(name string, value string) {
+	for i, header := range r.Headers {
+		if strings.EqualFold(header.Name, name) {
+			r.Headers[i].Value = value
+			return
+		}
+	}
+
+	r.Headers = append(r.Headers, importedHeader{name, value})
+}
+
+// RequestFile writes the request in the request file format, formatted the
+// way `hurl fmt` would
+func (r *importedRequest) RequestFile() []byte {
+	buffer := bytes.Buffer{}
+	buffer.WriteString(fmt.Sprintf("%s %s\n", r.Method, r.URL))
+
+	for _, header := range r.Headers {
+		buffer.WriteString(fmt.Sprintf("%s: %s\n", header.Name, header.Value))
+	}
+
+	switch {
+	case len(r.Multipart) > 0:
+		buffer.WriteString("\n")
+		for _, item := range r.Multipart {
+			valueKey := "value"
+			if item.IsFilePath {
+				valueKey = "filename"
+			}
+			buffer.WriteString(fmt.Sprintf("form-data; name=\"%s\"; %s=\"%s\"\n", item.Name, valueKey, item.Value))
+		}
+
+	case r.FileEmbed != "":
+		buffer.WriteString(fmt.Sprintf("\n@file=%s\n", r.FileEmbed))
+
+	case r.Body != "":
+		buffer.WriteString("\n")
+		buffer.WriteString(strings.TrimSuffix(r.Body, "\n"))
+		buffer.WriteString("\n")
+	}
+
+	formatted, err := FormatHurlFile(buffer.Bytes())
+	if err != nil {
+		return buffer.Bytes()
+	}
+
+	return formatted
+}
+
+// fileNameSlug turns a name into something safe to use as a file name, like
+// "Create user (admin)" to create-user-admin
+func fileNameSlug(name string) string {
+	slug := strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	if slug.Len() == 0 {
+		return "request"
+	}
+
+	return slug.String()
+}
+
+// templateVariableName turns a name into one hurl accepts as a template
+// variable, other tools allow names like base-url
+func templateVariableName(name string) string {
+	renamed := []byte{}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isAlpha(c) || isNum(c) || isUnderscore(c) {
+			renamed = append(renamed, c)
+		} else {
+			renamed = append(renamed, '_')
+		}
+	}
+	if len(renamed) == 0 || !isAlpha(renamed[0]) {
+		renamed = append([]byte("v"), renamed...)
+	}
+
+	return string(renamed)
+}
+
+// writeConfigFile writes a hurl.json using the .env file imported alongside
+// the request files, unless there already is one
+func writeConfigFile(dir string, envFileName string) (bool, error) {
+	// only the env file is set, the rest are left to their defaults
+	config, err := json.MarshalIndent(map[string]string{"env": envFileName}, "", "  ")
+	if err != nil {
+		return false, err
+	}
+
+	return writeNewFile(filepath.Join(dir, "hurl.json"), append(config, '\n'))
+}
+
+// writeNewFile writes a file without replacing one that's already there, so
+// files that have been edited since they were imported are kept
+func writeNewFile(path string, content []byte) (bool, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err == nil, err
+}
+
+// RunImport is the `hurl import` command, it turns requests from other tools
+// into request files
+func RunImport(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: hurl import curl|postman|openapi|har [flags] ...")
+		return 2
+	}
+
+	switch args[0] {
+	case "curl":
+		return runImportCurl(args[1:])
+	case "postman":
+		return runImportPostman(args[1:])
+	case "openapi":
+		return runImportOpenAPI(args[1:])
+	case "har":
+		return runImportHar(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "hurl import: unknown format %q, expected curl, postman, openapi or har\n", args[0])
+		return 2
+	}
+}
@@ -0,0 +1,569 @@
+package src
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// curl options that take a value but have nothing to carry over to a request
+// file, they're skipped along with their value
+var IGNORED_CURL_VALUE_OPTIONS = map[string]void{
+	"-o": member, "--output": member, "-w": member, "--write-out": member,
+	"-m": member, "--max-time": member, "--connect-timeout": member,
+	"--retry": member, "--retry-delay": member, "--retry-max-time": member,
+	"-c": member, "--cookie-jar": member, "--max-redirs": member,
+	"-D": member, "--dump-header": member, "--trace": member, "--trace-ascii": member,
+}
+
+// curl options that only change how curl itself behaves
+var IGNORED_CURL_OPTIONS = map[string]void{
+	"-s": member, "--silent": member, "-S": member, "--show-error": member,
+	"-L": member, "--location": member, "-v": member, "--verbose": member,
+	"-i": member, "--include": member, "-f": member, "--fail": member,
+	"-#": member, "--progress-bar": member, "--no-progress-meter": member,
+	"--http1.1": member, "--http2": member, "--compressed": member,
+	"-N": member, "--no-buffer": member, "-g": member, "--globoff": member,
+}
+
+var CURL_VALUE_OPTIONS = map[string]void{
+	"-X": member, "--request": member, "-H": member, "--header": member,
+	"-d": member, "--data": member, "--data-raw": member, "--data-binary": member,
+	"--data-ascii": member, "--data-urlencode": member, "--json": member,
+	"-F": member, "--form": member, "--form-string": member,
+	"-u": member, "--user": member, "-A": member, "--user-agent": member,
+	"-b": member, "--cookie": member, "-e": member, "--referer": member,
+	"--url": member,
+}
+
+// curlCommand holds the options of a curl command line that matter to the
+// request it sends
+type curlCommand struct {
+	method   string
+	url      string
+	headers  []importedHeader
+	data     []string
+	dataFile string
+	form     []MultiPartItem
+	get      bool
+	json     bool
+	insecure bool
+	warnings []string
+}
+
+// SplitShellWords splits a command line the way a POSIX shell would, with
+// quotes, backslash escapes, line continuations and $'...' strings
+func SplitShellWords(s string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			// a backslash before a newline continues the line
+			if s[i] == '\n' || (s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n') {
+				if s[i] == '\r' {
+					i++
+				}
+				continue
+			}
+			word.WriteByte(s[i])
+			inWord = true
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated ' quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			quoted, n, err := unquoteANSIC(s[i+2:])
+			if err != nil {
+				return nil, err
+			}
+			word.WriteString(quoted)
+			i += n + 2
+			inWord = true
+
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				// inside double quotes a backslash only escapes these
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, errors.New("unterminated \" quote")
+			}
+			inWord = true
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// unquoteANSIC decodes the inside of a $'...' string up to its closing quote
+// and returns the number of bytes read including the quote
+func unquoteANSIC(s string) (string, int, error) {
+	escapes := map[byte]string{
+		'n': "\n", 't': "\t", 'r': "\r", '\\': "\\", '\'': "'", '"': "\"",
+		'a': "\a", 'b': "\b", 'e': "\x1b", 'f': "\f", 'v': "\v", '?': "?",
+	}
+
+	out := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			return out.String(), i + 1, nil
+
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			if escape, ok := escapes[s[i]]; ok {
+				out.WriteString(escape)
+				continue
+			}
+
+			// \xHH and \uHHHH, browsers use these for anything that isn't
+			// printable
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+			if digits > 0 {
+				end := i + 1
+				for end < len(s) && end < i+1+digits && isHexDigit(s[end]) {
+					end++
+				}
+				if end > i+1 {
+					var r rune
+					fmt.Sscanf(s[i+1:end], "%x", &r)
+					if s[i] == 'x' {
+						out.WriteByte(byte(r))
+					} else {
+						out.WriteRune(r)
+					}
+					i = end - 1
+					continue
+				}
+			}
+
+			out.WriteByte('\\')
+			out.WriteByte(s[i])
+
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, errors.New("unterminated $' quote")
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// splitCurlOption splits an argument into the option and the value attached to
+// it, like -XPOST or -H'Accept: */*'
+func splitCurlOption(arg string) (string, string, bool) {
+	if strings.HasPrefix(arg, "--") || len(arg) <= 2 {
+		return arg, "", false
+	}
+
+	option := arg[:2]
+	if _, ok := CURL_VALUE_OPTIONS[option]; ok {
+		return option, arg[2:], true
+	}
+	if _, ok := IGNORED_CURL_VALUE_OPTIONS[option]; ok {
+		return option, arg[2:], true
+	}
+
+	return arg, "", false
+}
+
+// ParseCurlCommand reads the request a curl command line would send, options
+// that can't be carried over are kept as warnings
+func ParseCurlCommand(args []string) (*curlCommand, error) {
+	if len(args) > 0 && (args[0] == "curl" || strings.HasSuffix(args[0], "/curl") || args[0] == "curl.exe") {
+		args = args[1:]
+	}
+	args = append([]string{}, args...)
+
+	cmd := &curlCommand{}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if cmd.url != "" {
+				return nil, fmt.Errorf("more than one URL given: %s and %s", cmd.url, arg)
+			}
+			cmd.url = arg
+			continue
+		}
+
+		option, value, attached := splitCurlOption(arg)
+
+		// short options without values can be combined, like -sSL
+		if !attached && !strings.HasPrefix(option, "--") && len(option) > 2 {
+			combined := []string{}
+			for _, c := range option[1:] {
+				combined = append(combined, "-"+string(c))
+			}
+			args = append(args[:i], append(combined, args[i+1:]...)...)
+			i--
+			continue
+		}
+
+		_, takesValue := CURL_VALUE_OPTIONS[option]
+		_, ignoredValue := IGNORED_CURL_VALUE_OPTIONS[option]
+		if (takesValue || ignoredValue) && !attached {
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("option %s needs a value", option)
+			}
+			i++
+			value = args[i]
+		}
+
+		if ignoredValue {
+			continue
+		}
+		if _, ok := IGNORED_CURL_OPTIONS[option]; ok {
+			continue
+		}
+
+		err := cmd.applyOption(option, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.url == "" {
+		return nil, errors.New("no URL in curl command")
+	}
+
+	return cmd, nil
+}
+
+func (c *curlCommand) applyOption(option string, value string) error {
+	switch option {
+	case "-X", "--request":
+		c.method = strings.ToUpper(value)
+
+	case "-H", "--header":
+		name, headerValue, found := strings.Cut(value, ":")
+		if !found {
+			// curl sends "Name;" as an empty header
+			if strings.HasSuffix(name, ";") {
+				c.headers = append(c.headers, importedHeader{strings.TrimSuffix(name, ";"), ""})
+				return nil
+			}
+			return fmt.Errorf("invalid header %q", value)
+		}
+
+		// curl removes the header when it's given without a value
+		headerValue = strings.TrimSpace(headerValue)
+		if headerValue == "" {
+			return nil
+		}
+		c.headers = append(c.headers, importedHeader{strings.TrimSpace(name), headerValue})
+
+	case "-d", "--data", "--data-ascii", "--data-binary":
+		if strings.HasPrefix(value, "@") {
+			if value == "@-" {
+				return errors.New("data read from stdin can't be imported")
+			}
+			c.dataFile = value[1:]
+			return nil
+		}
+		c.data = append(c.data, value)
+
+	case "--data-raw":
+		c.data = append(c.data, value)
+
+	case "--json":
+		c.json = true
+		if strings.HasPrefix(value, "@") {
+			c.dataFile = value[1:]
+			return nil
+		}
+		c.data = append(c.data, value)
+
+	case "--data-urlencode":
+		encoded, err := urlencodeCurlData(value)
+		if err != nil {
+			return err
+		}
+		c.data = append(c.data, encoded)
+
+	case "-F", "--form", "--form-string":
+		item, err := c.parseFormOption(value, option == "--form-string")
+		if err != nil {
+			return err
+		}
+		c.form = append(c.form, item)
+
+	case "-u", "--user":
+		if !strings.Contains(value, ":") {
+			return fmt.Errorf("-u %s has no password, curl would prompt for it", value)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(value))
+		c.headers = append(c.headers, importedHeader{"Authorization", "Basic " + credentials})
+
+	case "-A", "--user-agent":
+		c.headers = append(c.headers, importedHeader{"User-Agent", value})
+
+	case "-e", "--referer":
+		c.headers = append(c.headers, importedHeader{"Referer", value})
+
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			c.warnings = append(c.warnings, fmt.Sprintf("ignoring cookie file %s, only cookies given inline are imported", value))
+			return nil
+		}
+		c.headers = append(c.headers, importedHeader{"Cookie", value})
+
+	case "--url":
+		if c.url != "" {
+			return fmt.Errorf("more than one URL given: %s and %s", c.url, value)
+		}
+		c.url = value
+
+	case "-G", "--get":
+		c.get = true
+
+	case "-k", "--insecure":
+		c.insecure = true
+
+	case "-I", "--head":
+		return errors.New("HEAD requests aren't supported")
+
+	default:
+		c.warnings = append(c.warnings, fmt.Sprintf("ignoring unknown curl option %s", option))
+	}
+
+	return nil
+}
+
+// parseFormOption reads a -F name=value, files are given as name=@path with
+// optional ;type= and ;filename= parameters that are dropped
+func (c *curlCommand) parseFormOption(value string, literal bool) (MultiPartItem, error) {
+	name, fieldValue, found := strings.Cut(value, "=")
+	if !found {
+		return MultiPartItem{}, fmt.Errorf("invalid form field %q", value)
+	}
+
+	if literal {
+		return MultiPartItem{Name: name, Value: fieldValue}, nil
+	}
+
+	if strings.HasPrefix(fieldValue, "<") {
+		return MultiPartItem{}, fmt.Errorf("form field %s reads its value from a file, which can't be imported", name)
+	}
+
+	if !strings.HasPrefix(fieldValue, "@") {
+		return MultiPartItem{Name: name, Value: fieldValue}, nil
+	}
+
+	path, params, hasParams := strings.Cut(fieldValue[1:], ";")
+	if hasParams {
+		c.warnings = append(c.warnings, fmt.Sprintf("ignoring ;%s on form field %s", params, name))
+	}
+	path = strings.Trim(path, "\"")
+
+	return MultiPartItem{Name: name, IsFilePath: true, Value: path}, nil
+}
+
+// urlencodeCurlData encodes a --data-urlencode value, which is either
+// content, =content or name=content
+func urlencodeCurlData(value string) (string, error) {
+	name, content, found := strings.Cut(value, "=")
+	if !found {
+		if strings.Contains(value, "@") {
+			return "", fmt.Errorf("--data-urlencode %s reads from a file, which can't be imported", value)
+		}
+		return url.QueryEscape(value), nil
+	}
+
+	if name == "" {
+		return url.QueryEscape(content), nil
+	}
+
+	return fmt.Sprintf("%s=%s", name, url.QueryEscape(content)), nil
+}
+
+// Request builds the request the curl command sends
+func (c *curlCommand) Request() (*importedRequest, error) {
+	rawURL := c.url
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	req := &importedRequest{URL: rawURL, Headers: c.headers}
+
+	hasData := len(c.data) > 0 || c.dataFile != ""
+	if hasData && len(c.form) > 0 {
+		return nil, errors.New("-d and -F can't be used together")
+	}
+
+	data := strings.Join(c.data, "&")
+
+	switch {
+	case c.get:
+		// -G sends the data in the query instead of the body
+		if c.dataFile != "" {
+			return nil, errors.New("-G can't be used with data read from a file")
+		}
+		if data != "" {
+			separator := "?"
+			if strings.Contains(req.URL, "?") {
+				separator = "&"
+			}
+			req.URL += separator + data
+		}
+		req.Method = "GET"
+
+	case len(c.form) > 0:
+		req.Method = "POST"
+		req.Multipart = c.form
+		// the boundary is added when the request is sent
+		req.setHeader("Content-Type", "multipart/form-data")
+
+	case hasData:
+		req.Method = "POST"
+		req.Body = data
+		req.FileEmbed = c.dataFile
+
+		if c.json {
+			if _, ok := req.header("Content-Type"); !ok {
+				req.Headers = append(req.Headers, importedHeader{"Content-Type", "application/json"})
+			}
+			if _, ok := req.header("Accept"); !ok {
+				req.Headers = append(req.Headers, importedHeader{"Accept", "application/json"})
+			}
+		} else if _, ok := req.header("Content-Type"); !ok {
+			req.Headers = append(req.Headers, importedHeader{"Content-Type", "application/x-www-form-urlencoded"})
+		}
+
+	default:
+		req.Method = "GET"
+	}
+
+	if c.method != "" && !c.get {
+		req.Method = c.method
+	}
+
+	if req.Method == "HEAD" || !isValidMethod(req.Method) || req.Method == "WS" || req.Method == "GRPC" {
+		return nil, fmt.Errorf("%s requests aren't supported", req.Method)
+	}
+
+	return req, nil
+}
+
+func runImportCurl(args []string) int {
+	flags := flag.NewFlagSet("import curl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl import curl [-o file] [command]")
+		fmt.Fprintln(os.Stderr, "the curl command is read from stdin when it isn't given")
+		flags.PrintDefaults()
+	}
+	outputPath := flags.String("o", "", "write the request file to `file` instead of stdout")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	// a single argument is a whole command line, several are already split by
+	// the shell
+	words := flags.Args()
+	if len(words) <= 1 {
+		commandLine := ""
+		if len(words) == 1 {
+			commandLine = words[0]
+		} else {
+			input, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+				return 1
+			}
+			commandLine = string(input)
+		}
+
+		words, err = SplitShellWords(commandLine)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+			return 1
+		}
+	}
+
+	cmd, err := ParseCurlCommand(words)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	req, err := cmd.Request()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	for _, warning := range cmd.warnings {
+		PrintWarning(errors.New(warning))
+	}
+	if cmd.insecure {
+		// TLS verification is an option of the run, not of the request
+		PrintWarning(errors.New("the curl command skips TLS verification with -k, which can't be written into a request file, run the request with hurl -k to do the same"))
+	}
+	if _, ok := req.header("Content-Type"); !ok && req.Method != "GET" && req.Method != "DELETE" {
+		PrintWarning(fmt.Errorf("%s requests need a Content-Type header, add one to the request file", req.Method))
+	}
+
+	requestFile := req.RequestFile()
+
+	if *outputPath == "" {
+		os.Stdout.Write(requestFile)
+		return 0
+	}
+
+	written, err := writeNewFile(*outputPath, requestFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+	if !written {
+		fmt.Fprintf(os.Stderr, "hurl import: %s already exists\n", *outputPath)
+		return 1
+	}
+
+	return 0
+}
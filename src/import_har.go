@@ -0,0 +1,300 @@
+package src
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// headers the browser sets for the connection rather than the request, hurl
+// sets its own
+var IGNORED_HAR_HEADERS = map[string]void{
+	"Host":              member,
+	"Content-Length":    member,
+	"Connection":        member,
+	"Keep-Alive":        member,
+	"Transfer-Encoding": member,
+	"Upgrade":           member,
+}
+
+// harImporter writes a request file for every entry of a HAR file that
+// matches the filters
+type harImporter struct {
+	dir         string
+	hosts       []string
+	methods     map[string]void
+	imported    int
+	existing    int
+	filtered    int
+	notImported []string
+	usedNames   map[string]void
+}
+
+func (h *harImporter) note(where string, what string) {
+	h.notImported = append(h.notImported, fmt.Sprintf("%s: %s", where, what))
+}
+
+// matches is whether the entry is one of the hosts and methods asked for,
+// hosts are patterns like *.example.com
+func (h *harImporter) matches(method string, requestURL *url.URL) bool {
+	if len(h.methods) > 0 {
+		if _, ok := h.methods[method]; !ok {
+			return false
+		}
+	}
+
+	if len(h.hosts) == 0 {
+		return true
+	}
+
+	host := strings.ToLower(requestURL.Hostname())
+	for _, pattern := range h.hosts {
+		if matched, _ := path.Match(pattern, host); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entryPath is the file an entry is written to, in a directory named after
+// its host
+func (h *harImporter) entryPath(method string, requestURL *url.URL) string {
+	dir := filepath.Join(h.dir, fileNameSlug(requestURL.Hostname()))
+
+	slug := fileNameSlug(method + " " + requestURL.Path)
+	path := filepath.Join(dir, slug+".txt")
+	for i := 2; ; i++ {
+		if _, ok := h.usedNames[path]; !ok {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.txt", slug, i))
+	}
+	h.usedNames[path] = member
+
+	return path
+}
+
+func (h *harImporter) importEntries(entries []*harEntry) error {
+	for i, entry := range entries {
+		method := strings.ToUpper(entry.Request.Method)
+		requestURL, err := url.Parse(entry.Request.URL)
+		if err != nil || (requestURL.Scheme != "http" && requestURL.Scheme != "https") {
+			h.filtered++
+			continue
+		}
+		if !h.matches(method, requestURL) {
+			h.filtered++
+			continue
+		}
+
+		where := fmt.Sprintf("entry %d %s %s", i+1, method, entry.Request.URL)
+		if !isValidMethod(method) || method == "WS" || method == "GRPC" {
+			h.note(where, fmt.Sprintf("%s requests aren't supported", method))
+			continue
+		}
+
+		path := h.entryPath(method, requestURL)
+		relative, err := filepath.Rel(h.dir, path)
+		if err != nil {
+			relative = path
+		}
+
+		notes := len(h.notImported)
+		req := h.request(entry.Request, filepath.ToSlash(relative))
+
+		written, err := writeNewFile(path, req.RequestFile())
+		if err != nil {
+			return err
+		}
+		// files that are already there were checked when they were imported
+		if !written {
+			h.notImported = h.notImported[:notes]
+			h.existing++
+			continue
+		}
+		h.imported++
+	}
+
+	return nil
+}
+
+// request builds the request file for an entry, the URL is kept as it was
+// sent and the headers in the order the browser sent them
+func (h *harImporter) request(harReq harRequest, where string) *importedRequest {
+	req := &importedRequest{Method: strings.ToUpper(harReq.Method), URL: harReq.URL}
+
+	for _, header := range harReq.Headers {
+		// HTTP/2 pseudo headers like :authority
+		if strings.HasPrefix(header.Name, ":") {
+			continue
+		}
+
+		name := http.CanonicalHeaderKey(header.Name)
+		if _, ok := IGNORED_HAR_HEADERS[name]; ok {
+			continue
+		}
+		req.Headers = append(req.Headers, importedHeader{name, header.Value})
+	}
+
+	if harReq.PostData != nil {
+		h.applyPostData(req, *harReq.PostData, where)
+	}
+
+	return req
+}
+
+func (h *harImporter) applyPostData(req *importedRequest, postData harPostData, where string) {
+	mediaType, params, _ := mime.ParseMediaType(postData.MimeType)
+	if contentType, ok := req.header("Content-Type"); ok && mediaType == "" {
+		mediaType, params, _ = mime.ParseMediaType(contentType)
+	}
+
+	if mediaType == "multipart/form-data" {
+		items := postData.Params
+		if len(items) == 0 {
+			items = parseMultipartText(postData.Text, params["boundary"])
+		}
+
+		for _, item := range items {
+			if item.FileName == "" {
+				req.Multipart = append(req.Multipart, MultiPartItem{Name: item.Name, Value: item.Value})
+				continue
+			}
+
+			// browsers don't keep the files that were uploaded
+			h.note(where, fmt.Sprintf("form field %s uploaded %s, the file isn't in the HAR", item.Name, item.FileName))
+			req.Multipart = append(req.Multipart, MultiPartItem{Name: item.Name, IsFilePath: true, Value: item.FileName})
+		}
+
+		if len(req.Multipart) > 0 {
+			// the boundary the browser sent with is replaced when the request
+			// is sent
+			req.setHeader("Content-Type", "multipart/form-data")
+			return
+		}
+	}
+
+	if postData.Text == "" {
+		return
+	}
+
+	req.Body = postData.Text
+	if _, ok := req.header("Content-Type"); !ok && postData.MimeType != "" {
+		req.Headers = append(req.Headers, importedHeader{"Content-Type", postData.MimeType})
+	}
+}
+
+// parseMultipartText reads the fields of a multipart body, browsers often
+// only record the body as it was sent
+func parseMultipartText(text string, boundary string) []harParam {
+	params := []harParam{}
+	if boundary == "" {
+		return params
+	}
+
+	reader := multipart.NewReader(strings.NewReader(text), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return params
+		}
+
+		param := harParam{Name: part.FormName(), FileName: part.FileName(), ContentType: part.Header.Get("Content-Type")}
+		if param.FileName == "" {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return params
+			}
+			param.Value = string(value)
+		}
+		params = append(params, param)
+	}
+}
+
+// commaList splits a flag like GET,POST, leaving out empty items
+func commaList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func runImportHar(args []string) int {
+	flags := flag.NewFlagSet("import har", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl import har [-o dir] [-host patterns] [-method methods] capture.har")
+		flags.PrintDefaults()
+	}
+	outputDir := flags.String("o", "", "directory to write the request files to, defaults to one named after the HAR file")
+	hosts := flags.String("host", "", "only import requests to these hosts, comma separated patterns like api.example.com,*.example.org")
+	methods := flags.String("method", "", "only import requests with these methods, comma separated like GET,POST")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	har := harFile{}
+	err = readJsonFile(flags.Arg(0), &har)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	dir := *outputDir
+	if dir == "" {
+		base := filepath.Base(flags.Arg(0))
+		dir = fileNameSlug(strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+
+	importer := &harImporter{dir: dir, methods: map[string]void{}, usedNames: map[string]void{}}
+	for _, host := range commaList(*hosts) {
+		importer.hosts = append(importer.hosts, strings.ToLower(host))
+	}
+	for _, method := range commaList(*methods) {
+		importer.methods[strings.ToUpper(method)] = member
+	}
+
+	err = importer.importEntries(har.Log.Entries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d new request(s) into %s", importer.imported, dir)
+	if importer.existing > 0 {
+		fmt.Fprintf(os.Stderr, ", %d already exist and were left as they are", importer.existing)
+	}
+	if importer.filtered > 0 {
+		fmt.Fprintf(os.Stderr, ", %d didn't match the filters", importer.filtered)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	if len(importer.notImported) > 0 {
+		fmt.Fprintln(os.Stderr, "\nto check:")
+		for _, note := range importer.notImported {
+			fmt.Fprintf(os.Stderr, "  %s\n", note)
+		}
+	}
+
+	return 0
+}
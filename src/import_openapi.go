@@ -0,0 +1,672 @@
+package src
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI writes path parameters as {name}
+var openAPIPathParameter = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// the order operations are written in when a path has several
+var OPENAPI_METHODS = []string{"get", "post", "put", "patch", "delete", "head", "options", "trace"}
+
+// media types to send a request body as, in order of preference, when an
+// operation accepts several
+var OPENAPI_MEDIA_TYPES = []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data", "text/plain"}
+
+// openAPIImporter writes a request file for every operation of a spec, the
+// spec is kept as decoded from YAML or JSON so $refs can be followed anywhere
+type openAPIImporter struct {
+	spec        map[string]any
+	dir         string
+	imported    int
+	existing    int
+	notImported []string
+	usedNames   map[string]void
+}
+
+func (o *openAPIImporter) note(where string, what string) {
+	o.notImported = append(o.notImported, fmt.Sprintf("%s: %s", where, what))
+}
+
+// resolve follows $refs within the spec until it reaches an object, refs to
+// other files aren't supported
+func (o *openAPIImporter) resolve(node any, where string) map[string]any {
+	object, _ := node.(map[string]any)
+
+	for i := 0; object != nil && i < 32; i++ {
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object
+		}
+
+		if !strings.HasPrefix(ref, "#/") {
+			o.note(where, fmt.Sprintf("reference to another file %s", ref))
+			return nil
+		}
+
+		var target any = o.spec
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			targetObject, _ := target.(map[string]any)
+			target = targetObject[token]
+		}
+
+		object, _ = target.(map[string]any)
+		if object == nil {
+			o.note(where, fmt.Sprintf("reference %s doesn't exist", ref))
+		}
+	}
+
+	return object
+}
+
+// serverURL is the first server's URL with its variables set to their
+// defaults
+func (o *openAPIImporter) serverURL() string {
+	servers, _ := o.spec["servers"].([]any)
+	if len(servers) == 0 {
+		return ""
+	}
+
+	server := o.resolve(servers[0], "servers")
+	serverURL, _ := server["url"].(string)
+	variables, _ := server["variables"].(map[string]any)
+
+	return openAPIPathParameter.ReplaceAllStringFunc(serverURL, func(match string) string {
+		variable, _ := variables[match[1:len(match)-1]].(map[string]any)
+		if defaultValue, ok := variable["default"]; ok {
+			return fmt.Sprint(defaultValue)
+		}
+		return match
+	})
+}
+
+type openAPIOperation struct {
+	Method    string
+	Path      string
+	Operation map[string]any
+
+	// parameters set on the path apply to all its operations
+	PathParameters []any
+}
+
+// operations lists the operations in the order of their paths, so files get
+// the same names every time the spec is imported
+func (o *openAPIImporter) operations() []openAPIOperation {
+	paths, _ := o.spec["paths"].(map[string]any)
+
+	sortedPaths := []string{}
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	operations := []openAPIOperation{}
+	for _, path := range sortedPaths {
+		pathItem := o.resolve(paths[path], path)
+		pathParameters, _ := pathItem["parameters"].([]any)
+
+		for _, method := range OPENAPI_METHODS {
+			operation, ok := pathItem[method].(map[string]any)
+			if ok {
+				operations = append(operations, openAPIOperation{strings.ToUpper(method), path, operation, pathParameters})
+			}
+		}
+	}
+
+	return operations
+}
+
+// operationPath is the file an operation is written to, in a directory named
+// after its first tag
+func (o *openAPIImporter) operationPath(operation openAPIOperation) string {
+	name, _ := operation.Operation["operationId"].(string)
+	if name == "" {
+		name = operation.Method + " " + operation.Path
+	}
+
+	dir := o.dir
+	tags, _ := operation.Operation["tags"].([]any)
+	if len(tags) > 0 {
+		dir = filepath.Join(dir, fileNameSlug(fmt.Sprint(tags[0])))
+	}
+
+	slug := operationIDSlug(name)
+	path := filepath.Join(dir, slug+".txt")
+	for i := 2; ; i++ {
+		if _, ok := o.usedNames[path]; !ok {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.txt", slug, i))
+	}
+	o.usedNames[path] = member
+
+	return path
+}
+
+// operationIDSlug is fileNameSlug with camelCase words split too, operation
+// IDs are usually written like createUserAdmin
+func operationIDSlug(operationID string) string {
+	words := strings.Builder{}
+	previous := ' '
+	for _, r := range operationID {
+		if unicode.IsUpper(r) && unicode.IsLower(previous) {
+			words.WriteByte(' ')
+		}
+		words.WriteRune(r)
+		previous = r
+	}
+
+	return fileNameSlug(words.String())
+}
+
+// parameters merges the operation's parameters over the path's, they're the
+// same parameter when both the name and location match
+func (o *openAPIImporter) parameters(operation openAPIOperation, where string) []map[string]any {
+	merged := []map[string]any{}
+	index := map[string]int{}
+
+	operationParameters, _ := operation.Operation["parameters"].([]any)
+	for _, node := range append(append([]any{}, operation.PathParameters...), operationParameters...) {
+		parameter := o.resolve(node, where)
+		if parameter == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%v:%v", parameter["in"], parameter["name"])
+		if i, ok := index[key]; ok {
+			merged[i] = parameter
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, parameter)
+	}
+
+	return merged
+}
+
+// parameterValue is the example of a parameter when it has one, otherwise a
+// variable named after it is used
+func (o *openAPIImporter) parameterValue(parameter map[string]any, where string) string {
+	if example, ok := parameter["example"]; ok {
+		return exampleString(example)
+	}
+
+	schema := o.resolve(parameter["schema"], where)
+	for _, key := range []string{"example", "default"} {
+		if example, ok := schema[key]; ok {
+			return exampleString(example)
+		}
+	}
+
+	name, _ := parameter["name"].(string)
+	return "{{" + templateVariableName(name) + "}}"
+}
+
+func exampleString(example any) string {
+	if s, ok := example.(string); ok {
+		return s
+	}
+
+	encoded, err := json.Marshal(example)
+	if err != nil {
+		return fmt.Sprint(example)
+	}
+
+	return string(encoded)
+}
+
+// request builds the request file for an operation
+func (o *openAPIImporter) request(operation openAPIOperation, where string) (*importedRequest, error) {
+	if !isValidMethod(operation.Method) {
+		return nil, fmt.Errorf("%s requests aren't supported", operation.Method)
+	}
+
+	// path parameters are always variables so each request can set them
+	path := openAPIPathParameter.ReplaceAllStringFunc(operation.Path, func(match string) string {
+		return "{{" + templateVariableName(match[1:len(match)-1]) + "}}"
+	})
+
+	req := &importedRequest{Method: operation.Method}
+	query := []string{}
+
+	for _, parameter := range o.parameters(operation, where) {
+		name, _ := parameter["name"].(string)
+		required, _ := parameter["required"].(bool)
+		if !required {
+			continue
+		}
+
+		switch parameter["in"] {
+		case "query":
+			query = append(query, url.QueryEscape(name)+"="+escapeFormValue(o.parameterValue(parameter, where)))
+		case "header":
+			req.Headers = append(req.Headers, importedHeader{name, o.parameterValue(parameter, where)})
+		case "cookie":
+			o.note(where, fmt.Sprintf("required cookie %s", name))
+		}
+	}
+
+	req.URL = "{{BASE_URL}}" + path
+	if len(query) > 0 {
+		req.URL += "?" + strings.Join(query, "&")
+	}
+
+	o.applySecurity(req, operation, where)
+
+	requestBody := o.resolve(operation.Operation["requestBody"], where)
+	if requestBody != nil {
+		o.applyRequestBody(req, requestBody, where)
+	}
+
+	return req, nil
+}
+
+// applySecurity adds the header for the first security requirement that
+// uses one, with the credentials left to variables
+func (o *openAPIImporter) applySecurity(req *importedRequest, operation openAPIOperation, where string) {
+	requirements, ok := operation.Operation["security"].([]any)
+	if !ok {
+		requirements, _ = o.spec["security"].([]any)
+	}
+
+	components, _ := o.spec["components"].(map[string]any)
+	schemes, _ := components["securitySchemes"].(map[string]any)
+
+	for _, requirement := range requirements {
+		names, _ := requirement.(map[string]any)
+
+		sortedNames := []string{}
+		for name := range names {
+			sortedNames = append(sortedNames, name)
+		}
+		sort.Strings(sortedNames)
+
+		for _, name := range sortedNames {
+			scheme := o.resolve(schemes[name], where)
+			schemeType, _ := scheme["type"].(string)
+			httpScheme, _ := scheme["scheme"].(string)
+
+			switch {
+			case schemeType == "http" && strings.EqualFold(httpScheme, "bearer"):
+				req.setHeader("Authorization", "Bearer {{TOKEN}}")
+			case schemeType == "http" && strings.EqualFold(httpScheme, "basic"):
+				req.setHeader("Authorization", "Basic {{BASIC_CREDENTIALS}}")
+			case schemeType == "oauth2" || schemeType == "openIdConnect":
+				req.setHeader("Authorization", "Bearer {{TOKEN}}")
+			case schemeType == "apiKey" && scheme["in"] == "header":
+				headerName, _ := scheme["name"].(string)
+				req.setHeader(headerName, "{{"+templateVariableName(strings.ToUpper(name))+"}}")
+			default:
+				o.note(where, fmt.Sprintf("%s security scheme %s", schemeType, name))
+				continue
+			}
+
+			return
+		}
+	}
+}
+
+func (o *openAPIImporter) applyRequestBody(req *importedRequest, requestBody map[string]any, where string) {
+	content, _ := requestBody["content"].(map[string]any)
+	if len(content) == 0 {
+		return
+	}
+
+	mediaType := ""
+	for _, preferred := range OPENAPI_MEDIA_TYPES {
+		if _, ok := content[preferred]; ok {
+			mediaType = preferred
+			break
+		}
+	}
+	if mediaType == "" {
+		mediaTypes := []string{}
+		for name := range content {
+			mediaTypes = append(mediaTypes, name)
+		}
+		sort.Strings(mediaTypes)
+		mediaType = mediaTypes[0]
+	}
+
+	media := o.resolve(content[mediaType], where)
+	example := o.mediaExample(media, where)
+
+	if mediaType == "multipart/form-data" {
+		req.setHeader("Content-Type", mediaType)
+		o.applyMultipartBody(req, media, example, where)
+		return
+	}
+
+	req.setHeader("Content-Type", mediaType)
+
+	// a Content-Type like application/problem+json is still JSON
+	isJson := lexerForMediaType(mediaType) == "json" || strings.HasSuffix(mediaType, "+json")
+
+	switch {
+	case isJson:
+		body, err := json.MarshalIndent(example, "", "  ")
+		if err != nil {
+			o.note(where, fmt.Sprintf("example body: %s", err.Error()))
+			return
+		}
+		req.Body = string(body)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		fields, _ := example.(map[string]any)
+		names := []string{}
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		pairs := []string{}
+		for _, name := range names {
+			pairs = append(pairs, url.QueryEscape(name)+"="+url.QueryEscape(exampleString(fields[name])))
+		}
+		req.Body = strings.Join(pairs, "&")
+
+	case example != nil && !isBinarySchema(o.resolve(media["schema"], where)):
+		req.Body = exampleString(example)
+
+	default:
+		// binary bodies are sent from a file next to the request file
+		req.FileEmbed = strings.TrimSuffix(filepath.Base(where), ".txt") + ".bin"
+		o.note(where, fmt.Sprintf("set the path of the %s body", mediaType))
+	}
+}
+
+// applyMultipartBody writes the properties of the body as form-data lines,
+// binary properties are read from files
+func (o *openAPIImporter) applyMultipartBody(req *importedRequest, media map[string]any, example any, where string) {
+	schema := o.resolve(media["schema"], where)
+	properties, _ := schema["properties"].(map[string]any)
+	fields, _ := example.(map[string]any)
+
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := o.resolve(properties[name], where)
+		if isBinarySchema(property) || isBinarySchema(o.resolve(property["items"], where)) {
+			req.Multipart = append(req.Multipart, MultiPartItem{Name: name, IsFilePath: true, Value: name})
+			o.note(where, fmt.Sprintf("set the filename of form field %s", name))
+			continue
+		}
+
+		req.Multipart = append(req.Multipart, MultiPartItem{Name: name, Value: exampleString(fields[name])})
+	}
+}
+
+func isBinarySchema(schema map[string]any) bool {
+	return schema["type"] == "string" && schema["format"] == "binary"
+}
+
+// mediaExample is the example given for a body, or one generated from its
+// schema when there isn't one
+func (o *openAPIImporter) mediaExample(media map[string]any, where string) any {
+	if example, ok := media["example"]; ok {
+		return example
+	}
+
+	examples, _ := media["examples"].(map[string]any)
+	names := []string{}
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		example := o.resolve(examples[names[0]], where)
+		if value, ok := example["value"]; ok {
+			return value
+		}
+	}
+
+	return o.schemaExample(media["schema"], where, map[string]void{})
+}
+
+// schemaExample builds an example value from a schema, using the examples,
+// defaults and enums in it where there are any. Schemas that refer to
+// themselves are left out the second time around
+func (o *openAPIImporter) schemaExample(node any, where string, refs map[string]void) any {
+	if object, ok := node.(map[string]any); ok {
+		if ref, ok := object["$ref"].(string); ok {
+			if _, cycle := refs[ref]; cycle {
+				return nil
+			}
+			refs[ref] = member
+			defer delete(refs, ref)
+		}
+	}
+
+	schema := o.resolve(node, where)
+	if schema == nil {
+		return nil
+	}
+
+	for _, key := range []string{"example", "default", "const"} {
+		if example, ok := schema[key]; ok {
+			return example
+		}
+	}
+	if examples, ok := schema["examples"].([]any); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		merged := map[string]any{}
+		for _, part := range allOf {
+			if fields, ok := o.schemaExample(part, where, refs).(map[string]any); ok {
+				for name, value := range fields {
+					merged[name] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if choices, ok := schema[key].([]any); ok && len(choices) > 0 {
+			return o.schemaExample(choices[0], where, refs)
+		}
+	}
+
+	schemaType := schema["type"]
+	// 3.1 allows a list of types, like [string, "null"]
+	if types, ok := schemaType.([]any); ok && len(types) > 0 {
+		schemaType = types[0]
+	}
+	if schemaType == nil {
+		if _, ok := schema["properties"]; ok {
+			schemaType = "object"
+		}
+	}
+
+	switch schemaType {
+	case "object":
+		example := map[string]any{}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range properties {
+			if value := o.schemaExample(property, where, refs); value != nil {
+				example[name] = value
+			}
+		}
+		return example
+
+	case "array":
+		item := o.schemaExample(schema["items"], where, refs)
+		if item == nil {
+			return []any{}
+		}
+		return []any{item}
+
+	case "integer", "number":
+		return 0
+
+	case "boolean":
+		return false
+
+	case "string":
+		formatExample, ok := map[any]string{
+			"date":      "2024-01-01",
+			"date-time": "2024-01-01T00:00:00Z",
+			"email":     "user@example.com",
+			"uuid":      "00000000-0000-0000-0000-000000000000",
+			"uri":       "https://example.com",
+		}[schema["format"]]
+		if ok {
+			return formatExample
+		}
+		return "string"
+	}
+
+	return nil
+}
+
+func runImportOpenAPI(args []string) int {
+	flags := flag.NewFlagSet("import openapi", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl import openapi [-o dir] spec.yaml")
+		flags.PrintDefaults()
+	}
+	outputDir := flags.String("o", "", "directory to write the request files to, defaults to one named after the spec's title")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	content, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	// JSON specs are YAML as well
+	spec := map[string]any{}
+	err = yaml.Unmarshal(content, &spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s: %s\n", flags.Arg(0), err.Error())
+		return 1
+	}
+
+	version, _ := spec["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		fmt.Fprintf(os.Stderr, "hurl import: %s isn't an OpenAPI 3 spec\n", flags.Arg(0))
+		return 1
+	}
+
+	dir := *outputDir
+	if dir == "" {
+		info, _ := spec["info"].(map[string]any)
+		dir = fileNameSlug(fmt.Sprint(info["title"]))
+	}
+
+	importer := &openAPIImporter{spec: spec, dir: dir, usedNames: map[string]void{}}
+
+	err = importer.importOperations()
+	if err == nil {
+		err = importer.writeEnvironment()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d new operation(s) into %s", importer.imported, dir)
+	if importer.existing > 0 {
+		fmt.Fprintf(os.Stderr, ", %d already exist and were left as they are", importer.existing)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	if len(importer.notImported) > 0 {
+		fmt.Fprintln(os.Stderr, "\nto check:")
+		for _, note := range importer.notImported {
+			fmt.Fprintf(os.Stderr, "  %s\n", note)
+		}
+	}
+
+	return 0
+}
+
+func (o *openAPIImporter) importOperations() error {
+	for _, operation := range o.operations() {
+		path := o.operationPath(operation)
+
+		relative, err := filepath.Rel(o.dir, path)
+		if err != nil {
+			relative = path
+		}
+		where := filepath.ToSlash(relative)
+
+		notes := len(o.notImported)
+		req, err := o.request(operation, where)
+		if err != nil {
+			o.note(where, err.Error())
+			continue
+		}
+
+		written, err := writeNewFile(path, req.RequestFile())
+		if err != nil {
+			return err
+		}
+		// files that are already there were checked when they were imported
+		if !written {
+			o.notImported = o.notImported[:notes]
+			o.existing++
+			continue
+		}
+		o.imported++
+	}
+
+	return nil
+}
+
+// writeEnvironment sets BASE_URL to the spec's server in a .env file that
+// hurl.json uses, neither is replaced if it's already there
+func (o *openAPIImporter) writeEnvironment() error {
+	baseURL := o.serverURL()
+	if baseURL == "" {
+		o.note("servers", "no server URL, set BASE_URL by hand")
+		return nil
+	}
+	if !strings.Contains(baseURL, "://") {
+		o.note("servers", fmt.Sprintf("server URL %s is relative, add the host to BASE_URL", baseURL))
+	}
+
+	content, err := godotenv.Marshal(map[string]string{"BASE_URL": strings.TrimSuffix(baseURL, "/")})
+	if err != nil {
+		return err
+	}
+
+	_, err = writeNewFile(filepath.Join(o.dir, ".env"), []byte(content+"\n"))
+	if err != nil {
+		return err
+	}
+
+	_, err = writeConfigFile(o.dir, ".env")
+	return err
+}
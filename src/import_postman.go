@@ -0,0 +1,699 @@
+package src
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Postman writes path variables as /:name
+var postmanPathVariable = regexp.MustCompile(`/:([A-Za-z_][A-Za-z0-9_-]*)`)
+
+// the parts of the Postman v2.1 collection format that have something to
+// carry over to request files
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanItem is a folder when it has items and a request otherwise
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+	Event   []postmanEvent  `json:"event"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *postmanBody      `json:"body"`
+	Auth   *postmanAuth      `json:"auth"`
+}
+
+// UnmarshalJSON reads a request given as only its URL as well
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var rawURL string
+	if json.Unmarshal(data, &rawURL) == nil {
+		*r = postmanRequest{Method: "GET", URL: postmanURL{Raw: rawURL}}
+		return nil
+	}
+
+	type request postmanRequest
+	return json.Unmarshal(data, (*request)(r))
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// UnmarshalJSON reads a URL given as a string as well
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var rawURL string
+	if json.Unmarshal(data, &rawURL) == nil {
+		*u = postmanURL{Raw: rawURL}
+		return nil
+	}
+
+	type postmanURLObject postmanURL
+	return json.Unmarshal(data, (*postmanURLObject)(u))
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	File       struct {
+		Src string `json:"src"`
+	} `json:"file"`
+	GraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// postmanKeyValue is used for headers, variables, form fields and auth
+// parameters, values can be any JSON value and files can be a list of paths
+type postmanKeyValue struct {
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	Disabled bool            `json:"disabled"`
+	Enabled  *bool           `json:"enabled"`
+	Type     string          `json:"type"`
+	Src      json.RawMessage `json:"src"`
+}
+
+func (kv postmanKeyValue) IsEnabled() bool {
+	return !kv.Disabled && (kv.Enabled == nil || *kv.Enabled)
+}
+
+func (kv postmanKeyValue) String() string {
+	return postmanString(kv.Value)
+}
+
+// postmanString is a JSON string as it is or any other JSON value as it's
+// written
+func postmanString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	return string(raw)
+}
+
+// postmanAuth holds the parameters of the auth type in use
+type postmanAuth struct {
+	Type   string
+	Params map[string]string
+}
+
+// UnmarshalJSON reads parameters as a list of key value pairs like v2.1 or as
+// an object like v2.0
+func (a *postmanAuth) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	a.Type = postmanString(fields["type"])
+	a.Params = map[string]string{}
+
+	params := fields[a.Type]
+	var list []postmanKeyValue
+	if json.Unmarshal(params, &list) == nil {
+		for _, kv := range list {
+			a.Params[kv.Key] = kv.String()
+		}
+		return nil
+	}
+
+	object := map[string]json.RawMessage{}
+	if json.Unmarshal(params, &object) == nil {
+		for key, value := range object {
+			a.Params[key] = postmanString(value)
+		}
+	}
+
+	return nil
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec json.RawMessage `json:"exec"`
+	} `json:"script"`
+}
+
+// HasScript reports whether the event runs any code, Postman keeps empty
+// scripts around once they've been opened
+func (e postmanEvent) HasScript() bool {
+	var lines []string
+	if json.Unmarshal(e.Script.Exec, &lines) != nil {
+		return strings.TrimSpace(postmanString(e.Script.Exec)) != ""
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")) != ""
+}
+
+type postmanEnvironment struct {
+	Name   string            `json:"name"`
+	Values []postmanKeyValue `json:"values"`
+}
+
+// postmanImporter writes a collection out as request files, keeping track of
+// what couldn't be imported for the summary
+type postmanImporter struct {
+	dir          string
+	imported     int
+	existing     []string
+	notImported  []string
+	usedNames    map[string]void
+	variableName map[string]string
+}
+
+// hurlVariableName turns a Postman variable name into one hurl accepts and
+// keeps track of the ones that were renamed
+func (p *postmanImporter) hurlVariableName(name string) string {
+	renamed := templateVariableName(name)
+	p.variableName[name] = renamed
+
+	return renamed
+}
+
+// convertVariables rewrites the {{ }} in text to names hurl accepts, Postman's
+// dynamic variables like {{$guid}} have no equivalent and are reported
+func (p *postmanImporter) convertVariables(text string, where string) string {
+	converted := strings.Builder{}
+
+	offset := 0
+	for _, variable := range FindTemplateVariables(text, Pos{1, 0}) {
+		converted.WriteString(text[offset:variable.Pos.Col])
+		offset = variable.End.Col
+
+		if strings.HasPrefix(variable.Name, "$") {
+			p.note(where, fmt.Sprintf("dynamic variable {{%s}}", variable.Name))
+			converted.WriteString(text[variable.Pos.Col:variable.End.Col])
+			continue
+		}
+
+		converted.WriteString("{{" + p.hurlVariableName(variable.Name) + "}}")
+	}
+	converted.WriteString(text[offset:])
+
+	return converted.String()
+}
+
+func (p *postmanImporter) note(where string, what string) {
+	p.notImported = append(p.notImported, fmt.Sprintf("%s: %s", where, what))
+}
+
+func (p *postmanImporter) noteScripts(where string, events []postmanEvent) {
+	for _, event := range events {
+		if !event.HasScript() {
+			continue
+		}
+
+		switch event.Listen {
+		case "prerequest":
+			p.note(where, "pre-request script")
+		case "test":
+			p.note(where, "test script")
+		default:
+			p.note(where, fmt.Sprintf("%s script", event.Listen))
+		}
+	}
+}
+
+// uniquePath adds a number to the name of a file when another item already
+// has it
+func (p *postmanImporter) uniquePath(dir string, name string, ext string) string {
+	slug := fileNameSlug(name)
+	path := filepath.Join(dir, slug+ext)
+	for i := 2; ; i++ {
+		if _, ok := p.usedNames[path]; !ok {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", slug, i, ext))
+	}
+	p.usedNames[path] = member
+
+	return path
+}
+
+// importItems writes every request under a folder, auth is inherited from the
+// folders above unless an item sets its own
+func (p *postmanImporter) importItems(items []postmanItem, dir string, auth *postmanAuth) error {
+	for _, item := range items {
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+
+		if item.Request == nil {
+			folder := p.uniquePath(dir, item.Name, "")
+			p.noteScripts(p.relative(folder)+"/", item.Event)
+
+			err := p.importItems(item.Item, folder, itemAuth)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if item.Request.Auth != nil {
+			itemAuth = item.Request.Auth
+		}
+
+		path := p.uniquePath(dir, item.Name, ".txt")
+		where := p.relative(path)
+		p.noteScripts(where, item.Event)
+
+		req, err := p.request(*item.Request, itemAuth, where)
+		if err != nil {
+			p.note(where, err.Error())
+			continue
+		}
+
+		written, err := writeNewFile(path, req.RequestFile())
+		if err != nil {
+			return err
+		}
+		if !written {
+			p.existing = append(p.existing, where)
+			continue
+		}
+		p.imported++
+	}
+
+	return nil
+}
+
+func (p *postmanImporter) relative(path string) string {
+	relative, err := filepath.Rel(p.dir, path)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(relative)
+}
+
+// request turns a Postman request into a request, things that can't be carried
+// over are noted
+func (p *postmanImporter) request(postman postmanRequest, auth *postmanAuth, where string) (*importedRequest, error) {
+	method := strings.ToUpper(postman.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if !isValidMethod(method) || method == "WS" || method == "GRPC" {
+		return nil, fmt.Errorf("%s requests aren't supported", method)
+	}
+
+	req := &importedRequest{Method: method, URL: p.requestURL(postman.URL, where)}
+
+	for _, header := range postman.Header {
+		if !header.IsEnabled() {
+			continue
+		}
+		req.Headers = append(req.Headers, importedHeader{p.convertVariables(header.Key, where), p.convertVariables(header.String(), where)})
+	}
+
+	if auth != nil {
+		p.applyAuth(req, *auth, where)
+	}
+
+	if postman.Body != nil {
+		p.applyBody(req, *postman.Body, where)
+	}
+
+	bodyVariables := strings.Contains(req.Body, "{{")
+	for _, item := range req.Multipart {
+		bodyVariables = bodyVariables || strings.Contains(item.Value, "{{")
+	}
+	if bodyVariables {
+		p.note(where, "variables in the body, hurl sends bodies as written")
+	}
+
+	return req, nil
+}
+
+// requestURL fills in path variables from their values, the ones without a
+// value become variables
+func (p *postmanImporter) requestURL(postmanURL postmanURL, where string) string {
+	values := map[string]string{}
+	for _, variable := range postmanURL.Variable {
+		values[variable.Key] = variable.String()
+	}
+
+	rawURL := postmanPathVariable.ReplaceAllStringFunc(postmanURL.Raw, func(match string) string {
+		name := match[2:]
+		if value, ok := values[name]; ok && value != "" {
+			return "/" + value
+		}
+		p.note(where, fmt.Sprintf("path variable :%s has no value, it's set from {{%s}} instead", name, p.hurlVariableName(name)))
+		return "/{{" + name + "}}"
+	})
+	rawURL = p.convertVariables(rawURL, where)
+
+	// Postman sends URLs without a scheme over http
+	if !strings.HasPrefix(rawURL, "{{") && !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	return rawURL
+}
+
+func (p *postmanImporter) applyAuth(req *importedRequest, auth postmanAuth, where string) {
+	param := func(name string) string {
+		return p.convertVariables(auth.Params[name], where)
+	}
+
+	switch auth.Type {
+	case "", "noauth":
+
+	case "bearer":
+		req.setHeader("Authorization", "Bearer "+param("token"))
+
+	case "basic":
+		credentials := param("username") + ":" + param("password")
+		if strings.Contains(credentials, "{{") {
+			p.note(where, "basic auth using variables, it can't be encoded ahead of time so set the Authorization header by hand")
+			return
+		}
+		req.setHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+
+	case "apikey":
+		key, value := param("key"), param("value")
+		if auth.Params["in"] != "query" {
+			req.setHeader(key, value)
+			return
+		}
+
+		separator := "?"
+		if strings.Contains(req.URL, "?") {
+			separator = "&"
+		}
+		req.URL += separator + key + "=" + value
+
+	default:
+		p.note(where, fmt.Sprintf("%s auth", auth.Type))
+	}
+}
+
+func (p *postmanImporter) applyBody(req *importedRequest, body postmanBody, where string) {
+	contentType := ""
+
+	switch body.Mode {
+	case "raw":
+		req.Body = p.convertVariables(body.Raw, where)
+		contentType = map[string]string{
+			"json": "application/json", "xml": "application/xml", "html": "text/html",
+			"javascript": "application/javascript", "text": "text/plain",
+		}[body.Options.Raw.Language]
+		if contentType == "" {
+			contentType = "text/plain"
+		}
+
+	case "urlencoded":
+		pairs := []string{}
+		for _, field := range body.URLEncoded {
+			if field.IsEnabled() {
+				pairs = append(pairs, escapeFormValue(p.convertVariables(field.Key, where))+"="+escapeFormValue(p.convertVariables(field.String(), where)))
+			}
+		}
+		req.Body = strings.Join(pairs, "&")
+		contentType = "application/x-www-form-urlencoded"
+
+	case "formdata":
+		for _, field := range body.FormData {
+			if !field.IsEnabled() {
+				continue
+			}
+
+			if field.Type != "file" {
+				req.Multipart = append(req.Multipart, MultiPartItem{Name: field.Key, Value: p.convertVariables(field.String(), where)})
+				continue
+			}
+
+			// a file field can have several files
+			paths := []string{}
+			if json.Unmarshal(field.Src, &paths) != nil {
+				paths = []string{postmanString(field.Src)}
+			}
+			for _, path := range paths {
+				if path == "" {
+					p.note(where, fmt.Sprintf("form field %s has no file selected", field.Key))
+					continue
+				}
+				req.Multipart = append(req.Multipart, MultiPartItem{Name: field.Key, IsFilePath: true, Value: path})
+			}
+		}
+		// the boundary Postman sent with is replaced when the request is sent
+		req.setHeader("Content-Type", "multipart/form-data")
+		return
+
+	case "file":
+		if body.File.Src == "" {
+			p.note(where, "file body has no file selected")
+			return
+		}
+		req.FileEmbed = body.File.Src
+		contentType = "application/octet-stream"
+
+	case "graphql":
+		req.Body = GRAPHQL_BODY_TAG + "\n" + p.convertVariables(body.GraphQL.Query, where)
+		if strings.TrimSpace(body.GraphQL.Variables) != "" {
+			req.Body = strings.TrimRight(req.Body, "\n") + "\n\n" + GRAPHQL_VARIABLES_TAG + "\n" + p.convertVariables(body.GraphQL.Variables, where)
+		}
+		contentType = "application/json"
+
+	default:
+		return
+	}
+
+	if _, ok := req.header("Content-Type"); !ok {
+		req.Headers = append(req.Headers, importedHeader{"Content-Type", contentType})
+	}
+}
+
+// escapeFormValue percent encodes a form key or value, leaving the {{ }} in it
+// as they are
+func escapeFormValue(value string) string {
+	escaped := strings.Builder{}
+
+	offset := 0
+	for _, variable := range FindTemplateVariables(value, Pos{1, 0}) {
+		escaped.WriteString(url.QueryEscape(value[offset:variable.Pos.Col]))
+		escaped.WriteString(value[variable.Pos.Col:variable.End.Col])
+		offset = variable.End.Col
+	}
+	escaped.WriteString(url.QueryEscape(value[offset:]))
+
+	return escaped.String()
+}
+
+// writeEnvFile writes variables to a .env file, the collection's variables
+// are defaults that the environment's override
+func (p *postmanImporter) writeEnvFile(path string, collection []postmanKeyValue, environment []postmanKeyValue) error {
+	variables := map[string]string{}
+	for _, values := range [][]postmanKeyValue{collection, environment} {
+		for _, variable := range values {
+			if variable.IsEnabled() {
+				variables[p.hurlVariableName(variable.Key)] = variable.String()
+			}
+		}
+	}
+
+	content, err := godotenv.Marshal(variables)
+	if err != nil {
+		return err
+	}
+
+	written, err := writeNewFile(path, []byte(content+"\n"))
+	if err != nil {
+		return err
+	}
+	if !written {
+		p.existing = append(p.existing, p.relative(path))
+	}
+
+	return nil
+}
+
+// writeConfigFile points hurl.json at the first environment's .env file
+func (p *postmanImporter) writeConfigFile(envFileName string) error {
+	written, err := writeConfigFile(p.dir, envFileName)
+	if err != nil {
+		return err
+	}
+	if !written {
+		p.existing = append(p.existing, "hurl.json")
+	}
+
+	return nil
+}
+
+func runImportPostman(args []string) int {
+	flags := flag.NewFlagSet("import postman", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl import postman [-o dir] [-env environment.json] collection.json")
+		flags.PrintDefaults()
+	}
+	outputDir := flags.String("o", "", "directory to write the request files to, defaults to one named after the collection")
+	var environmentPaths stringsFlag
+	flags.Var(&environmentPaths, "env", "Postman environment to write as a .env file, can be repeated")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	collection := postmanCollection{}
+	err = readJsonFile(flags.Arg(0), &collection)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	environments := []postmanEnvironment{}
+	for _, path := range environmentPaths {
+		environment := postmanEnvironment{}
+		err = readJsonFile(path, &environment)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+			return 1
+		}
+		environments = append(environments, environment)
+	}
+
+	dir := *outputDir
+	if dir == "" {
+		dir = fileNameSlug(collection.Info.Name)
+	}
+
+	importer := &postmanImporter{dir: dir, usedNames: map[string]void{}, variableName: map[string]string{}}
+	importer.noteScripts("collection", collection.Event)
+
+	err = importer.importItems(collection.Item, dir, collection.Auth)
+	if err == nil {
+		err = importer.importEnvironments(collection.Variable, environments)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl import: %s\n", err.Error())
+		return 1
+	}
+
+	importer.printSummary()
+
+	return 0
+}
+
+// importEnvironments writes a .env file for every environment, or one for the
+// collection's variables when there are none
+func (p *postmanImporter) importEnvironments(collection []postmanKeyValue, environments []postmanEnvironment) error {
+	envFileNames := []string{}
+
+	if len(environments) == 0 && len(collection) > 0 {
+		err := p.writeEnvFile(filepath.Join(p.dir, ".env"), collection, nil)
+		if err != nil {
+			return err
+		}
+		envFileNames = append(envFileNames, ".env")
+	}
+
+	for _, environment := range environments {
+		path := p.uniquePath(p.dir, environment.Name, ".env")
+		err := p.writeEnvFile(path, collection, environment.Values)
+		if err != nil {
+			return err
+		}
+		envFileNames = append(envFileNames, filepath.Base(path))
+	}
+
+	if len(envFileNames) == 0 {
+		return nil
+	}
+
+	if len(envFileNames) > 1 {
+		fmt.Fprintf(os.Stderr, "hurl.json uses %s, change \"env\" to switch to %s\n", envFileNames[0], strings.Join(envFileNames[1:], " or "))
+	}
+
+	return p.writeConfigFile(envFileNames[0])
+}
+
+func (p *postmanImporter) printSummary() {
+	fmt.Fprintf(os.Stderr, "imported %d request(s) into %s\n", p.imported, p.dir)
+
+	renamed := []string{}
+	for name, hurlName := range p.variableName {
+		if name != hurlName {
+			renamed = append(renamed, fmt.Sprintf("  {{%s}} is {{%s}}", name, hurlName))
+		}
+	}
+	sort.Strings(renamed)
+	if len(renamed) > 0 {
+		fmt.Fprintln(os.Stderr, "\nrenamed variables:")
+		fmt.Fprintln(os.Stderr, strings.Join(renamed, "\n"))
+	}
+
+	if len(p.existing) > 0 {
+		fmt.Fprintln(os.Stderr, "\nalready exist, left as they are:")
+		for _, path := range p.existing {
+			fmt.Fprintf(os.Stderr, "  %s\n", path)
+		}
+	}
+
+	if len(p.notImported) > 0 {
+		fmt.Fprintln(os.Stderr, "\nnot imported:")
+		for _, note := range p.notImported {
+			fmt.Fprintf(os.Stderr, "  %s\n", note)
+		}
+	}
+}
+
+func readJsonFile(path string, v any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(content, v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package src
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// request bodies are only kept up to this size in JSON output, uploads can be
+// far bigger than anything worth putting in a JSON document
+const MAX_JSON_REQUEST_BODY = 1 << 20
+
+type JsonHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type JsonBody struct {
+	// "text" or "base64"
+	Encoding  string `json:"encoding"`
+	Content   string `json:"content"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	File      string `json:"file,omitempty"`
+
+	// size on the wire when the body was sent with a Content-Encoding
+	CompressedSize int64 `json:"compressed_size,omitempty"`
+}
+
+type JsonRequest struct {
+	Method  string       `json:"method"`
+	URL     string       `json:"url"`
+	Headers []JsonHeader `json:"headers"`
+	Body    *JsonBody    `json:"body,omitempty"`
+}
+
+type JsonResponse struct {
+	Protocol   string       `json:"protocol"`
+	StatusCode int          `json:"status_code"`
+	Status     string       `json:"status"`
+	Headers    []JsonHeader `json:"headers"`
+	Body       *JsonBody    `json:"body,omitempty"`
+}
+
+// JsonTiming holds durations in milliseconds from the start of the request,
+// phases that didn't happen (e.g. DNS for an IP address) are left out
+type JsonTiming struct {
+	DNS          *float64 `json:"dns_ms,omitempty"`
+	Connect      *float64 `json:"connect_ms,omitempty"`
+	TLSHandshake *float64 `json:"tls_handshake_ms,omitempty"`
+	FirstByte    *float64 `json:"first_byte_ms,omitempty"`
+	Total        float64  `json:"total_ms"`
+}
+
+type JsonOutput struct {
+	Request  JsonRequest   `json:"request"`
+	Response *JsonResponse `json:"response,omitempty"`
+	Timing   JsonTiming    `json:"timing"`
+	Warnings []string      `json:"warnings"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ErrReportedInJson is returned once an error has been written to the JSON
+// document, so it shouldn't be printed again
+var ErrReportedInJson = fmt.Errorf("error reported in JSON output")
+
+// jsonHeaders lists the headers the way they're printed, in the -header-order
+// and filtered by -include-headers and -exclude-headers
+func jsonHeaders(header http.Header, wireOrder []string) []JsonHeader {
+	// repeated headers like Set-Cookie get an entry per value
+	headers := []JsonHeader{}
+	for _, name := range orderHeaderNames(header, wireOrder) {
+		if !isHeaderShown(name) {
+			continue
+		}
+
+		for _, value := range header[name] {
+			headers = append(headers, JsonHeader{name, value})
+		}
+	}
+
+	return headers
+}
+
+func jsonBody(body []byte, size int64) *JsonBody {
+	if utf8.Valid(body) && !bytes.ContainsRune(body, 0) {
+		return &JsonBody{Encoding: "text", Content: string(body), Size: size}
+	}
+
+	return &JsonBody{Encoding: "base64", Content: base64.StdEncoding.EncodeToString(body), Size: size}
+}
+
+func millisecondsSince(start time.Time, t time.Time) *float64 {
+	if t.IsZero() {
+		return nil
+	}
+
+	ms := float64(t.Sub(start).Microseconds()) / 1000
+	return &ms
+}
+
+// limitedBuffer keeps the first max bytes written to it and drops the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (l *limitedBuffer) Write(b []byte) (int, error) {
+	remaining := l.max - l.Len()
+	if len(b) > remaining {
+		l.truncated = true
+		l.Buffer.Write(b[:max(remaining, 0)])
+		return len(b), nil
+	}
+
+	return l.Buffer.Write(b)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// OutputJson sends the request and prints a single JSON document describing
+// the request as sent, the response, timings and any warnings
+func (h HurlOutput) OutputJson(client *http.Client, req *http.Request) error {
+	output := JsonOutput{
+		Request: JsonRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+		},
+	}
+
+	// capture the request body as the transport reads it
+	sentBody := &limitedBuffer{max: MAX_JSON_REQUEST_BODY}
+	sentBodySize := &countingWriter{}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = teeReadCloser{io.TeeReader(req.Body, io.MultiWriter(sentBody, sentBodySize)), req.Body}
+	}
+
+	var start, dnsDone, connectDone, tlsDone, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		DNSDone:              func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectDone:          func(string, string, error) { connectDone = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start = time.Now()
+	res, err := client.Do(req)
+
+	output.Request.Headers = jsonHeaders(req.Header, nil)
+	if isHeaderShown("Host") {
+		output.Request.Headers = append(output.Request.Headers, JsonHeader{"Host", req.Host})
+	}
+	if sentBodySize.n > 0 {
+		output.Request.Body = jsonBody(sentBody.Bytes(), sentBodySize.n)
+		output.Request.Body.Truncated = sentBody.truncated
+	}
+
+	if err == nil {
+		output.Response, err = h.jsonResponse(res)
+	}
+
+	output.Timing = JsonTiming{
+		DNS:          millisecondsSince(start, dnsDone),
+		Connect:      millisecondsSince(start, connectDone),
+		TLSHandshake: millisecondsSince(start, tlsDone),
+		FirstByte:    millisecondsSince(start, firstByte),
+		Total:        *millisecondsSince(start, time.Now()),
+	}
+
+	// the trace and HAR file are closed before the document is printed so
+	// errors writing them end up in it
+	CloseTrace()
+	if harErr := CloseHar(); err == nil {
+		err = harErr
+	}
+
+	return printJsonOutput(output, err)
+}
+
+// OutputJsonError prints the JSON document for a request that failed before it
+// was sent, hurlFile is nil when the request file couldn't be parsed
+func OutputJsonError(hurlFile *HurlFile, err error) error {
+	output := JsonOutput{Request: JsonRequest{Headers: []JsonHeader{}}}
+	if hurlFile != nil {
+		output.Request.Method = hurlFile.Method
+		output.Request.URL = hurlFile.URL.String()
+	}
+
+	return printJsonOutput(output, err)
+}
+
+func printJsonOutput(output JsonOutput, err error) error {
+	output.Warnings = Warnings
+	if output.Warnings == nil {
+		output.Warnings = []string{}
+	}
+
+	if err != nil {
+		output.Error = err.Error()
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encodeErr := encoder.Encode(output)
+	if encodeErr != nil {
+		return encodeErr
+	}
+
+	if err != nil {
+		return ErrReportedInJson
+	}
+
+	return nil
+}
+
+func (h HurlOutput) jsonResponse(res *http.Response) (*JsonResponse, error) {
+	defer res.Body.Close()
+
+	jsonRes := &JsonResponse{
+		Protocol:   res.Proto,
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Headers:    jsonHeaders(res.Header, ResponseHeaderOrder()),
+	}
+
+	if h.Config.HeadersOnly {
+		return jsonRes, nil
+	}
+
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return jsonRes, err
+	}
+
+	compressedSize := int64(0)
+	contentEncoding := res.Header.Get("Content-Encoding")
+	if !h.Config.RawBody && len(contentEncodings(contentEncoding)) > 0 && len(bodyBytes) > 0 {
+		compressedSize = int64(len(bodyBytes))
+
+		bodyBytes, err = Decompress(bodyBytes, contentEncoding)
+		if err != nil {
+			return jsonRes, err
+		}
+	}
+
+	bodyOutputPath := h.Config.BodyOutputPath
+	if h.Config.RemoteName {
+		bodyOutputPath = RemoteName(*res)
+	}
+
+	// written as is, the body is left out of the document
+	if bodyOutputPath != "" {
+		err := os.WriteFile(bodyOutputPath, bodyBytes, 0644)
+		if err != nil {
+			return jsonRes, err
+		}
+
+		jsonRes.Body = &JsonBody{Encoding: "file", File: bodyOutputPath, Size: int64(len(bodyBytes)), CompressedSize: compressedSize}
+
+		if h.Config.Checksum != "" {
+			err := VerifyChecksum(bodyOutputPath, h.Config.Checksum)
+			if err != nil {
+				return jsonRes, err
+			}
+		}
+
+		return jsonRes, h.jsonGraphQLErrors(bodyBytes)
+	}
+
+	jsonRes.Body = jsonBody(bodyBytes, int64(len(bodyBytes)))
+	jsonRes.Body.CompressedSize = compressedSize
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType == "application/json" || mediaType == "application/graphql-response+json" {
+		return jsonRes, h.jsonGraphQLErrors(bodyBytes)
+	}
+
+	return jsonRes, nil
+}
+
+// jsonGraphQLErrors fails a GraphQL request whose response has errors, the
+// same as it does when the response is printed
+func (h HurlOutput) jsonGraphQLErrors(body []byte) error {
+	if !h.GraphQL {
+		return nil
+	}
+
+	_, errCount, err := FormatGraphQLBody(body)
+	if err != nil {
+		return err
+	}
+
+	return graphQLErrorsError(errCount)
+}
@@ -0,0 +1,345 @@
+package src
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+)
+
+// rule IDs diagnostics are reported with
+const (
+	RULE_SYNTAX               = "syntax"
+	RULE_INVALID_URL          = "invalid-url"
+	RULE_INVALID_VARIABLE     = "invalid-variable"
+	RULE_UNDEFINED_VARIABLE   = "undefined-variable"
+	RULE_BODY_VARIABLE        = "body-variable"
+	RULE_MISSING_CONTENT_TYPE = "missing-content-type"
+	RULE_DUPLICATE_HEADER     = "duplicate-header"
+	RULE_INVALID_JSON         = "invalid-json"
+	RULE_FILE_NOT_FOUND       = "file-not-found"
+)
+
+// Diagnostic is a problem found in a request file, End is just past the last
+// character of the problem on the same line
+type Diagnostic struct {
+	Pos      Pos
+	End      Pos
+	Severity string
+	Rule     string
+	Message  string
+}
+
+func newDiagnostic(pos Pos, length int, severity string, rule string, message string) Diagnostic {
+	return Diagnostic{
+		Pos:      pos,
+		End:      Pos{pos.Line, pos.Col + max(length, 1)},
+		Severity: severity,
+		Rule:     rule,
+		Message:  message,
+	}
+}
+
+// TemplateVariable is a {{ }} in a request file
+type TemplateVariable struct {
+	Name string
+	Pos  Pos
+	End  Pos
+}
+
+// FindTemplateVariables finds the {{ }} in a line, pos is where the line
+// starts
+func FindTemplateVariables(line string, pos Pos) []TemplateVariable {
+	variables := []TemplateVariable{}
+
+	offset := 0
+	for {
+		start := strings.Index(line[offset:], "{{")
+		if start == -1 {
+			return variables
+		}
+		start += offset
+
+		end := strings.Index(line[start:], "}}")
+		if end == -1 {
+			end = len(line)
+		} else {
+			end += start + 2
+		}
+
+		variables = append(variables, TemplateVariable{
+			Name: strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line[start:end], "{{"), "}}")),
+			Pos:  Pos{pos.Line, pos.Col + start},
+			End:  Pos{pos.Line, pos.Col + end},
+		})
+
+		offset = end
+	}
+}
+
+// offsetToPos turns a byte offset into the body into a position in the file
+func offsetToPos(body []BodyLineNode, offset int64) Pos {
+	for _, line := range body {
+		if offset <= int64(len(line.Text)) {
+			return Pos{line.Pos.Line, int(offset) + 1}
+		}
+
+		// the newline after the line
+		offset -= int64(len(line.Text)) + 1
+	}
+
+	if len(body) == 0 {
+		return Pos{1, 1}
+	}
+
+	last := body[len(body)-1]
+	return Pos{last.Pos.Line, len(last.Text) + 1}
+}
+
+// LintHurlFile finds problems in a request file that would stop it being
+// sent or make it send something other than what was meant. Variables are
+// looked up in the environment, so the env file from hurl.json should be
+// loaded first
+func LintHurlFile(src []byte) []Diagnostic {
+	ast, err := ParseHurlAST(src)
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return []Diagnostic{newDiagnostic(syntaxErr.Pos, 1, SEVERITY_ERROR, RULE_SYNTAX, syntaxErr.Message)}
+		}
+
+		return []Diagnostic{newDiagnostic(Pos{1, 1}, 1, SEVERITY_ERROR, RULE_SYNTAX, err.Error())}
+	}
+
+	diagnostics := []Diagnostic{}
+	diagnostics = append(diagnostics, lintVariables(ast)...)
+	diagnostics = append(diagnostics, lintURL(ast)...)
+	diagnostics = append(diagnostics, lintHeaders(ast)...)
+	diagnostics = append(diagnostics, lintBody(ast)...)
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Pos.Line != diagnostics[j].Pos.Line {
+			return diagnostics[i].Pos.Line < diagnostics[j].Pos.Line
+		}
+		return diagnostics[i].Pos.Col < diagnostics[j].Pos.Col
+	})
+
+	return diagnostics
+}
+
+func lintVariables(ast *HurlAST) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	variables := FindTemplateVariables(ast.RequestLine.URL, ast.RequestLine.URLPos)
+	for _, header := range ast.Headers {
+		variables = append(variables, FindTemplateVariables(header.Name, header.NamePos)...)
+		variables = append(variables, FindTemplateVariables(header.Value, header.ValuePos)...)
+	}
+
+	for _, variable := range variables {
+		length := variable.End.Col - variable.Pos.Col
+
+		err := validateTemplateVariable([]byte(variable.Name))
+		if err != nil {
+			diagnostics = append(diagnostics, newDiagnostic(variable.Pos, length, SEVERITY_ERROR, RULE_INVALID_VARIABLE, err.Error()))
+			continue
+		}
+
+		if os.Getenv(variable.Name) == "" {
+			diagnostics = append(diagnostics, newDiagnostic(variable.Pos, length, SEVERITY_WARNING, RULE_UNDEFINED_VARIABLE, fmt.Sprintf("environment variable %s isn't set", variable.Name)))
+		}
+	}
+
+	// bodies are sent as they're written
+	for _, line := range ast.Body {
+		for _, variable := range FindTemplateVariables(line.Text, line.Pos) {
+			diagnostics = append(diagnostics, newDiagnostic(variable.Pos, variable.End.Col-variable.Pos.Col, SEVERITY_WARNING, RULE_BODY_VARIABLE, "variables aren't replaced in bodies, this is sent as written"))
+		}
+	}
+
+	return diagnostics
+}
+
+func lintURL(ast *HurlAST) []Diagnostic {
+	requestURL := ast.RequestLine.URL
+	if strings.Contains(requestURL, "{{") {
+		return []Diagnostic{}
+	}
+
+	_, err := url.ParseRequestURI(requestURL)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return []Diagnostic{newDiagnostic(ast.RequestLine.URLPos, len(requestURL), SEVERITY_ERROR, RULE_INVALID_URL, fmt.Sprintf("invalid URL: %s", err.Error()))}
+	}
+
+	return []Diagnostic{}
+}
+
+func lintHeaders(ast *HurlAST) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	seen := make(map[string]HeaderNode)
+
+	for _, header := range ast.Headers {
+		key := strings.ToLower(header.Name)
+
+		if first, exists := seen[key]; exists {
+			message := fmt.Sprintf("%s is already set on line %d, only the last value is sent", header.Name, first.NamePos.Line)
+			diagnostics = append(diagnostics, newDiagnostic(header.NamePos, len(header.Name), SEVERITY_WARNING, RULE_DUPLICATE_HEADER, message))
+			continue
+		}
+
+		seen[key] = header
+	}
+
+	return diagnostics
+}
+
+func lintBody(ast *HurlAST) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	lines := bodyLineTexts(ast)
+	if len(trimBlankLines(lines)) == 0 || ast.IsWebSocket() {
+		return diagnostics
+	}
+
+	_, hasContentType := ast.Header("Content-Type")
+	if !hasContentType && ast.RequestLine.Method != "GRPC" && !ast.IsGraphQL() {
+		first := ast.Body[0]
+		diagnostics = append(diagnostics, newDiagnostic(first.Pos, len(first.Text), SEVERITY_WARNING, RULE_MISSING_CONTENT_TYPE, "body without a Content-Type header is sent as text/plain"))
+	}
+
+	for _, line := range ast.Body {
+		path := extractFileEmbedPath(line.Text)
+		if path == "" {
+			continue
+		}
+
+		_, err := os.Stat(path)
+		if err != nil {
+			pos := Pos{line.Pos.Line, len("@file=") + 1}
+			diagnostics = append(diagnostics, newDiagnostic(pos, len(path), SEVERITY_ERROR, RULE_FILE_NOT_FOUND, fmt.Sprintf("file %s doesn't exist", path)))
+		}
+
+		return diagnostics
+	}
+
+	mediaType := ast.MediaType()
+
+	switch {
+	case ast.IsGraphQL():
+		sc := bufio.NewScanner(strings.NewReader(string(ast.BodyText())))
+		_, err := parseGraphQL(sc)
+		if err != nil {
+			diagnostics = append(diagnostics, newDiagnostic(ast.Body[0].Pos, len(ast.Body[0].Text), SEVERITY_ERROR, RULE_SYNTAX, err.Error()))
+		}
+
+	case ast.RequestLine.Method == "GRPC" || lexerForMediaType(mediaType) == "json":
+		if strings.Contains(string(ast.BodyText()), "{{") {
+			break
+		}
+
+		var v any
+		err := json.Unmarshal(ast.BodyText(), &v)
+		if err != nil {
+			pos := ast.Body[0].Pos
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				pos = offsetToPos(ast.Body, syntaxErr.Offset-1)
+			}
+
+			diagnostics = append(diagnostics, newDiagnostic(pos, 1, SEVERITY_ERROR, RULE_INVALID_JSON, fmt.Sprintf("invalid JSON body: %s", err.Error())))
+		}
+
+	case mediaType == "multipart/form-data":
+		for _, line := range ast.Body {
+			if isBlank(line.Text) {
+				continue
+			}
+
+			item, err := parseMultiPartLine(line.Text)
+			if err != nil {
+				diagnostics = append(diagnostics, newDiagnostic(Pos{line.Pos.Line, leadingSpace(line.Text) + 1}, len(strings.TrimSpace(line.Text)), SEVERITY_ERROR, RULE_SYNTAX, err.Error()))
+				continue
+			}
+
+			if !item.IsFilePath {
+				continue
+			}
+
+			_, err = os.Stat(item.Value)
+			if err != nil {
+				col := strings.Index(line.Text, item.Value) + 1
+				diagnostics = append(diagnostics, newDiagnostic(Pos{line.Pos.Line, col}, len(item.Value), SEVERITY_ERROR, RULE_FILE_NOT_FOUND, fmt.Sprintf("file %s doesn't exist", item.Value)))
+			}
+		}
+
+	}
+
+	return diagnostics
+}
+
+// RunLint is the `hurl lint` command, it exits with 1 if any file has errors
+func RunLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hurl lint [files...]")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	_, err = loadConfigFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl lint: %s\n", err.Error())
+		return 1
+	}
+
+	errorStyle := color.New(color.Bold, color.FgRed).SprintFunc()
+	warningStyle := color.New(color.Bold, color.FgYellow).SprintFunc()
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "hurl lint: %s\n", err.Error())
+			status = 1
+			continue
+		}
+
+		for _, diagnostic := range LintHurlFile(src) {
+			severity := warningStyle(diagnostic.Severity)
+			if diagnostic.Severity == SEVERITY_ERROR {
+				severity = errorStyle(diagnostic.Severity)
+				status = 1
+			}
+
+			fmt.Printf("%s:%s: %s: %s [%s]\n", path, diagnostic.Pos, severity, diagnostic.Message, diagnostic.Rule)
+		}
+	}
+
+	return status
+}
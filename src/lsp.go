@@ -0,0 +1,586 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/joho/godotenv"
+)
+
+const LSP_SEND_COMMAND = "hurl.send"
+
+var lspMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "WS", "GRPC"}
+
+var lspHeaderNames = []string{
+	"Accept", "Accept-Encoding", "Accept-Language", "Authorization", "Cache-Control",
+	"Connection", "Content-Encoding", "Content-Type", "Cookie", "If-Match",
+	"If-Modified-Since", "If-None-Match", "Origin", "Range", "Referer",
+	"Sec-WebSocket-Protocol", "User-Agent", "X-Api-Key", "X-Request-Id",
+}
+
+var lspContentTypes = []string{
+	"application/json", "application/x-www-form-urlencoded", "multipart/form-data",
+	"application/graphql", "application/xml", "application/octet-stream",
+	"text/plain", "text/html", "text/csv",
+}
+
+type lspMessage struct {
+	JsonRpc string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type lspCommand struct {
+	Title     string `json:"title"`
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+// LSP completion item kinds
+const (
+	LSP_KIND_VALUE    = 12
+	LSP_KIND_KEYWORD  = 14
+	LSP_KIND_VARIABLE = 6
+	LSP_KIND_PROPERTY = 10
+)
+
+// lspServer answers requests from an editor over stdio, documents are kept
+// as the editor last sent them
+type lspServer struct {
+	reader *bufio.Reader
+	writer io.Writer
+
+	writeMu   sync.Mutex
+	documents map[string]string
+
+	envFilePath string
+}
+
+// RunLSP is the `hurl lsp` command, a language server for request files
+// speaking LSP over stdin and stdout
+func RunLSP(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: hurl lsp")
+		return 2
+	}
+
+	// stdout carries the protocol, nothing else can be printed to it
+	CollectWarnings = true
+
+	configFile, err := loadConfigFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl lsp: %s\n", err.Error())
+		return 1
+	}
+
+	server := &lspServer{
+		reader:      bufio.NewReader(os.Stdin),
+		writer:      os.Stdout,
+		documents:   make(map[string]string),
+		envFilePath: configFile.EnvFilePath,
+	}
+
+	err = server.serve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl lsp: %s\n", err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (s *lspServer) serve() error {
+	for {
+		message, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Method == "exit" {
+			return nil
+		}
+
+		s.handle(message)
+	}
+}
+
+// read reads a message framed with a Content-Length header
+func (s *lspServer) read() (*lspMessage, error) {
+	contentLength := -1
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %s", value)
+			}
+		}
+	}
+
+	if contentLength < 0 {
+		return nil, errors.New("message without a Content-Length")
+	}
+
+	content := make([]byte, contentLength)
+	_, err := io.ReadFull(s.reader, content)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &lspMessage{}
+	err = json.Unmarshal(content, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func (s *lspServer) write(message lspMessage) {
+	message.JsonRpc = "2.0"
+
+	content, err := json.Marshal(message)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hurl lsp: %s\n", err.Error())
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func (s *lspServer) reply(id *json.RawMessage, result any) {
+	if result == nil {
+		// null results still have to be sent
+		result = json.RawMessage("null")
+	}
+
+	s.write(lspMessage{ID: id, Result: result})
+}
+
+func (s *lspServer) replyError(id *json.RawMessage, code int, err error) {
+	s.write(lspMessage{ID: id, Error: &lspError{Code: code, Message: err.Error()}})
+}
+
+func (s *lspServer) notify(method string, params any) {
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+
+	s.write(lspMessage{Method: method, Params: paramsBytes})
+}
+
+func (s *lspServer) handle(message *lspMessage) {
+	switch message.Method {
+	case "initialize":
+		s.reply(message.ID, map[string]any{
+			"capabilities": map[string]any{
+				// the whole document is sent on every change
+				"textDocumentSync": 1,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{"{", ":", " "},
+				},
+				"hoverProvider":      true,
+				"codeActionProvider": true,
+				"executeCommandProvider": map[string]any{
+					"commands": []string{LSP_SEND_COMMAND},
+				},
+			},
+			"serverInfo": map[string]string{"name": "hurl"},
+		})
+
+	case "shutdown":
+		s.reply(message.ID, nil)
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(message.Params, &params) == nil {
+			s.documents[params.TextDocument.URI] = params.TextDocument.Text
+			s.publishDiagnostics(params.TextDocument.URI)
+		}
+
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(message.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			s.documents[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.publishDiagnostics(params.TextDocument.URI)
+		}
+
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(message.Params, &params) == nil {
+			delete(s.documents, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", map[string]any{"uri": params.TextDocument.URI, "diagnostics": []lspDiagnostic{}})
+		}
+
+	case "textDocument/completion":
+		var params lspTextDocumentPosition
+		if json.Unmarshal(message.Params, &params) != nil {
+			s.reply(message.ID, nil)
+			return
+		}
+		s.reply(message.ID, s.complete(params))
+
+	case "textDocument/hover":
+		var params lspTextDocumentPosition
+		if json.Unmarshal(message.Params, &params) != nil {
+			s.reply(message.ID, nil)
+			return
+		}
+		s.reply(message.ID, s.hover(params))
+
+	case "textDocument/codeAction":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(message.Params, &params) != nil {
+			s.reply(message.ID, nil)
+			return
+		}
+		s.reply(message.ID, []lspCommand{{Title: "Send request", Command: LSP_SEND_COMMAND, Arguments: []any{params.TextDocument.URI}}})
+
+	case "workspace/executeCommand":
+		var params struct {
+			Command   string   `json:"command"`
+			Arguments []string `json:"arguments"`
+		}
+		if json.Unmarshal(message.Params, &params) != nil || params.Command != LSP_SEND_COMMAND || len(params.Arguments) != 1 {
+			s.replyError(message.ID, -32602, errors.New("expected hurl.send with a document URI"))
+			return
+		}
+
+		uri := params.Arguments[0]
+		text, exists := s.documents[uri]
+		if !exists {
+			s.replyError(message.ID, -32602, fmt.Errorf("document isn't open: %s", uri))
+			return
+		}
+
+		// sending can take a while, keep answering the editor meanwhile
+		go func(id *json.RawMessage) {
+			result, err := s.send(uri, text)
+			if err != nil {
+				s.replyError(id, -32603, err)
+				return
+			}
+			s.reply(id, result)
+		}(message.ID)
+
+	default:
+		// requests have to be answered, notifications are ignored
+		if message.ID != nil {
+			s.replyError(message.ID, -32601, fmt.Errorf("method not supported: %s", message.Method))
+		}
+	}
+}
+
+func (s *lspServer) publishDiagnostics(uri string) {
+	text := s.documents[uri]
+	lines := strings.Split(text, "\n")
+
+	diagnostics := []lspDiagnostic{}
+	for _, diagnostic := range LintHurlFile([]byte(text)) {
+		severity := 2
+		if diagnostic.Severity == SEVERITY_ERROR {
+			severity = 1
+		}
+
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range: lspRange{
+				Start: toLspPosition(lines, diagnostic.Pos),
+				End:   toLspPosition(lines, diagnostic.End),
+			},
+			Severity: severity,
+			Code:     diagnostic.Rule,
+			Source:   "hurl",
+			Message:  diagnostic.Message,
+		})
+	}
+
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diagnostics})
+}
+
+// toLspPosition turns a 1 based line and byte column into the 0 based line
+// and UTF-16 character LSP uses
+func toLspPosition(lines []string, pos Pos) lspPosition {
+	line := pos.Line - 1
+	if line < 0 || line >= len(lines) {
+		return lspPosition{Line: max(line, 0)}
+	}
+
+	text := lines[line]
+	byteCol := min(max(pos.Col-1, 0), len(text))
+
+	return lspPosition{Line: line, Character: len(utf16.Encode([]rune(text[:byteCol])))}
+}
+
+// byteColumn turns a UTF-16 character in a line into a byte offset
+func byteColumn(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+
+	return len(line)
+}
+
+// lineAt is the text of the line the position is on and the byte offset of
+// the position in it
+func (s *lspServer) lineAt(uri string, position lspPosition) ([]string, string, int) {
+	lines := strings.Split(s.documents[uri], "\n")
+	if position.Line >= len(lines) {
+		return lines, "", 0
+	}
+
+	line := strings.TrimSuffix(lines[position.Line], "\r")
+	return lines, line, byteColumn(line, position.Character)
+}
+
+// envVariables are the variables defined in the env file from hurl.json
+func (s *lspServer) envVariables() map[string]string {
+	if s.envFilePath == "" {
+		return map[string]string{}
+	}
+
+	variables, err := godotenv.Read(s.envFilePath)
+	if err != nil {
+		return map[string]string{}
+	}
+
+	return variables
+}
+
+func (s *lspServer) lookupVariable(name string) (string, bool) {
+	if value, exists := s.envVariables()[name]; exists {
+		return value, true
+	}
+
+	return os.LookupEnv(name)
+}
+
+func completionItems(labels []string, kind int, detail string) []lspCompletionItem {
+	items := []lspCompletionItem{}
+	for _, label := range labels {
+		items = append(items, lspCompletionItem{Label: label, Kind: kind, Detail: detail})
+	}
+
+	return items
+}
+
+// complete offers what could go where the cursor is: methods at the start of
+// the request line, header names and Content-Type values in the headers and
+// env file variables inside {{ }}
+func (s *lspServer) complete(params lspTextDocumentPosition) []lspCompletionItem {
+	lines, line, col := s.lineAt(params.TextDocument.URI, params.Position)
+	beforeCursor := line[:col]
+
+	// inside an unclosed {{
+	if open := strings.LastIndex(beforeCursor, "{{"); open != -1 && !strings.Contains(beforeCursor[open:], "}}") {
+		names := []string{}
+		for name := range s.envVariables() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return completionItems(names, LSP_KIND_VARIABLE, "env file variable")
+	}
+
+	if params.Position.Line == 0 {
+		if !strings.Contains(beforeCursor, " ") {
+			return completionItems(lspMethods, LSP_KIND_KEYWORD, "method")
+		}
+		return []lspCompletionItem{}
+	}
+
+	// headers run until the first blank line
+	for i := 1; i < params.Position.Line && i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			return []lspCompletionItem{}
+		}
+	}
+
+	name, _, afterColon := strings.Cut(beforeCursor, ":")
+	if !afterColon {
+		return completionItems(lspHeaderNames, LSP_KIND_PROPERTY, "header")
+	}
+
+	if strings.EqualFold(strings.TrimSpace(name), "Content-Type") {
+		return completionItems(lspContentTypes, LSP_KIND_VALUE, "media type")
+	}
+
+	return []lspCompletionItem{}
+}
+
+// hover shows the value a {{ }} under the cursor resolves to
+func (s *lspServer) hover(params lspTextDocumentPosition) any {
+	lines, line, col := s.lineAt(params.TextDocument.URI, params.Position)
+
+	for _, variable := range FindTemplateVariables(line, Pos{params.Position.Line + 1, 1}) {
+		if col < variable.Pos.Col-1 || col >= variable.End.Col-1 {
+			continue
+		}
+
+		contents := fmt.Sprintf("`%s` isn't set", variable.Name)
+		if value, exists := s.lookupVariable(variable.Name); exists {
+			contents = fmt.Sprintf("`%s` = `%s`", variable.Name, value)
+		}
+
+		return map[string]any{
+			"contents": map[string]string{"kind": "markdown", "value": contents},
+			"range": lspRange{
+				Start: toLspPosition(lines, variable.Pos),
+				End:   toLspPosition(lines, variable.End),
+			},
+		}
+	}
+
+	return nil
+}
+
+// send sends the request in a document as the editor has it by running hurl
+// on a copy of it, the result is the -output-format json document
+func (s *lspServer) send(uri string, text string) (json.RawMessage, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	requestFile, err := os.CreateTemp("", "hurl-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(requestFile.Name())
+
+	_, err = requestFile.WriteString(text)
+	requestFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+
+	cmd := exec.Command(executable, "-output-format", "json", requestFile.Name())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// run from the document's directory when it's on disk so relative file
+	// paths in it work
+	parsedURI, err := url.Parse(uri)
+	if err == nil && parsedURI.Scheme == "file" {
+		cmd.Dir = filepath.Dir(parsedURI.Path)
+	}
+
+	err = cmd.Run()
+
+	var output JsonOutput
+	if jsonErr := json.Unmarshal(stdout.Bytes(), &output); jsonErr != nil {
+		if stderr.Len() > 0 {
+			return nil, errors.New(strings.TrimSpace(strings.TrimPrefix(stderr.String(), "hurl: ")))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, jsonErr
+	}
+
+	// 1 is an error message and 3 information
+	if output.Response != nil {
+		summary := fmt.Sprintf("%s %s in %.0fms", output.Response.Protocol, output.Response.Status, output.Timing.Total)
+		s.notify("window/showMessage", map[string]any{"type": 3, "message": summary})
+	} else if output.Error != "" {
+		s.notify("window/showMessage", map[string]any{"type": 1, "message": output.Error})
+	}
+
+	return stdout.Bytes(), nil
+}
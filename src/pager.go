@@ -0,0 +1,150 @@
+package src
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+const DEFAULT_PAGER = "less -R"
+
+// output held back while a pager is running, nil when stdout isn't paged
+var pager *pagedOutput
+
+type pagedOutput struct {
+	mode   string
+	stdout *os.File
+	writer *os.File
+	done   chan struct{}
+	buffer bytes.Buffer
+}
+
+// StartPager holds back everything printed to stdout so it can be shown
+// through $PAGER by ClosePager. mode is "auto" to only page output taller than
+// the terminal, "always" or "never", nothing is paged unless stdout is a
+// terminal
+func StartPager(mode string) error {
+	if mode == "never" || !isTerminal(os.Stdout) {
+		return nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	p := &pagedOutput{
+		mode:   mode,
+		stdout: os.Stdout,
+		writer: writer,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		io.Copy(&p.buffer, reader)
+		reader.Close()
+		close(p.done)
+	}()
+
+	os.Stdout = writer
+	pager = p
+
+	return nil
+}
+
+// StdoutIsTerminal is whether the real stdout is a terminal, os.Stdout is a
+// pipe to the held back output while the pager is running
+func StdoutIsTerminal() bool {
+	if pager != nil {
+		return isTerminal(pager.stdout)
+	}
+
+	return isTerminal(os.Stdout)
+}
+
+// ClosePager restores stdout and shows the held back output, through the
+// pager if it doesn't fit on the screen
+func ClosePager() error {
+	if pager == nil {
+		return nil
+	}
+
+	p := pager
+	pager = nil
+
+	os.Stdout = p.stdout
+	p.writer.Close()
+	<-p.done
+
+	output := p.buffer.Bytes()
+
+	width, height := terminalSize(p.stdout)
+	if p.mode != "always" && (height == 0 || outputHeight(output, width) < height) {
+		_, err := p.stdout.Write(output)
+		return err
+	}
+
+	pagerCommand := os.Getenv("PAGER")
+	if pagerCommand == "" {
+		pagerCommand = DEFAULT_PAGER
+	}
+
+	cmd := shellCommand(pagerCommand)
+	cmd.Stdin = bytes.NewReader(output)
+	cmd.Stdout = p.stdout
+	cmd.Stderr = os.Stderr
+
+	// keep colours and the output on screen once less exits, like git does
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+
+	err := cmd.Run()
+	if err != nil {
+		// the pager ran and the output has been seen, 127 is the shell not
+		// finding the pager
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() != 127 {
+			return nil
+		}
+
+		// a missing pager shouldn't lose the response
+		PrintWarning(fmt.Errorf("couldn't run pager %q: %w", pagerCommand, err))
+		_, err = p.stdout.Write(output)
+		return err
+	}
+
+	return nil
+}
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// outputHeight counts the lines output takes up on a terminal width columns
+// wide, colour escapes take up no space and long lines wrap
+func outputHeight(output []byte, width int) int {
+	height := 0
+	for _, line := range bytes.Split(bytes.TrimSuffix(output, []byte("\n")), []byte("\n")) {
+		columns := utf8.RuneCount(ansiEscape.ReplaceAll(line, nil))
+		if width <= 0 || columns <= width {
+			height++
+			continue
+		}
+
+		height += (columns + width - 1) / width
+	}
+
+	return height
+}
+
+// terminalSizeFromEnv falls back to $COLUMNS and $LINES
+func terminalSizeFromEnv() (int, int) {
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	height, _ := strconv.Atoi(os.Getenv("LINES"))
+	return width, height
+}
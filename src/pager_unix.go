@@ -0,0 +1,25 @@
+//go:build !windows
+
+package src
+
+import (
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalSize is the width and height of the terminal f is attached to
+func terminalSize(f *os.File) (int, int) {
+	size, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil || size.Row == 0 {
+		return terminalSizeFromEnv()
+	}
+
+	return int(size.Col), int(size.Row)
+}
+
+// shellCommand runs $PAGER through the shell so it can include arguments
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package src
+
+import (
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// terminalSize is the width and height of the console f is attached to
+func terminalSize(f *os.File) (int, int) {
+	var info windows.ConsoleScreenBufferInfo
+	err := windows.GetConsoleScreenBufferInfo(windows.Handle(f.Fd()), &info)
+	if err != nil {
+		return terminalSizeFromEnv()
+	}
+
+	width := int(info.Window.Right - info.Window.Left + 1)
+	height := int(info.Window.Bottom - info.Window.Top + 1)
+
+	return width, height
+}
+
+// shellCommand runs $PAGER through cmd so it can include arguments
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
@@ -0,0 +1,47 @@
+package src
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// ProgressReader counts the bytes read from a request body so the spinner can
+// show how much of an upload has been sent
+type ProgressReader struct {
+	r     io.Reader
+	read  atomic.Int64
+	Total int64
+}
+
+func NewProgressReader(r io.Reader, total int64) *ProgressReader {
+	return &ProgressReader{r: r, Total: total}
+}
+
+func (p *ProgressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read.Add(int64(n))
+	return n, err
+}
+
+func (p *ProgressReader) Close() error {
+	if closer, ok := p.r.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (p *ProgressReader) BytesRead() int64 {
+	return p.read.Load()
+}
+
+func (p *ProgressReader) String() string {
+	read := p.BytesRead()
+	if p.Total <= 0 {
+		return formatBytes(read)
+	}
+
+	percent := float64(read) / float64(p.Total) * 100
+	return fmt.Sprintf("%s / %s (%.0f%%)", formatBytes(read), formatBytes(p.Total), percent)
+}
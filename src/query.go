@@ -0,0 +1,1000 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// jsonObject keeps the keys of a JSON object in the order they were written so
+// filtered output reads like the response it came from
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	buffer := bytes.Buffer{}
+	buffer.WriteByte('{')
+
+	for i, key := range o.keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		keyBytes, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(keyBytes)
+		buffer.WriteByte(':')
+
+		valueBytes, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(valueBytes)
+	}
+
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func decodeOrderedJson(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			array := []any{}
+			for decoder.More() {
+				value, err := decodeOrderedJson(decoder)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+
+			_, err := decoder.Token()
+			return array, err
+		}
+
+		object := &jsonObject{values: make(map[string]any)}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			key := keyToken.(string)
+			value, err := decodeOrderedJson(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, exists := object.values[key]; !exists {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+
+		_, err := decoder.Token()
+		return object, err
+
+	default:
+		return t, nil
+	}
+}
+
+func parseOrderedJson(body []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	value, err := decodeOrderedJson(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("response body is not JSON: %w", err)
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("response body is not JSON: unexpected data after top-level value")
+	}
+
+	return value, nil
+}
+
+// a selector maps one value to the values it selects, queries are evaluated
+// by running each selector over the results of the one before it
+type selector func(value any) ([]any, error)
+
+func applySelectors(selectors []selector, values []any) ([]any, error) {
+	for _, s := range selectors {
+		next := []any{}
+		for _, value := range values {
+			selected, err := s(value)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, selected...)
+		}
+		values = next
+	}
+
+	return values, nil
+}
+
+// missing is what a key or index that doesn't exist selects, jq gives null
+// where JSONPath matches nothing
+var jqMissing = []any{nil}
+var jsonPathMissing = []any{}
+
+func selectKey(key string, optional bool, missing []any) selector {
+	return func(value any) ([]any, error) {
+		switch v := value.(type) {
+		case *jsonObject:
+			if child, exists := v.values[key]; exists {
+				return []any{child}, nil
+			}
+			return missing, nil
+		case nil:
+			return missing, nil
+		}
+
+		if optional {
+			return []any{}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with \"%s\"", jsonTypeName(value), key)
+	}
+}
+
+func selectIndex(index int, optional bool, missing []any) selector {
+	return func(value any) ([]any, error) {
+		switch v := value.(type) {
+		case []any:
+			i := index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return missing, nil
+			}
+			return []any{v[i]}, nil
+		case nil:
+			return missing, nil
+		}
+
+		if optional {
+			return []any{}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with number", jsonTypeName(value))
+	}
+}
+
+// selectSlice selects part of an array, as a new array for jq or as each
+// element for JSONPath when spread
+func selectSlice(start *int, end *int, optional bool, spread bool) selector {
+	return func(value any) ([]any, error) {
+		array, ok := value.([]any)
+		if !ok {
+			if optional || value == nil {
+				return []any{}, nil
+			}
+			return nil, fmt.Errorf("cannot slice %s", jsonTypeName(value))
+		}
+
+		from, to := 0, len(array)
+		if start != nil {
+			from = clampIndex(*start, len(array))
+		}
+		if end != nil {
+			to = clampIndex(*end, len(array))
+		}
+		if from > to {
+			from = to
+		}
+
+		if spread {
+			return array[from:to], nil
+		}
+		return []any{array[from:to]}, nil
+	}
+}
+
+func clampIndex(i int, length int) int {
+	if i < 0 {
+		i += length
+	}
+	return min(max(i, 0), length)
+}
+
+func selectAll(optional bool) selector {
+	return func(value any) ([]any, error) {
+		switch v := value.(type) {
+		case []any:
+			return v, nil
+		case *jsonObject:
+			values := make([]any, 0, len(v.keys))
+			for _, key := range v.keys {
+				values = append(values, v.values[key])
+			}
+			return values, nil
+		}
+
+		if optional {
+			return []any{}, nil
+		}
+		return nil, fmt.Errorf("cannot iterate over %s", jsonTypeName(value))
+	}
+}
+
+// selectDescendants selects the value and everything nested inside it, used
+// for JSONPath's `..`
+func selectDescendants(value any) ([]any, error) {
+	values := []any{value}
+
+	switch v := value.(type) {
+	case []any:
+		for _, child := range v {
+			descendants, _ := selectDescendants(child)
+			values = append(values, descendants...)
+		}
+	case *jsonObject:
+		for _, key := range v.keys {
+			descendants, _ := selectDescendants(v.values[key])
+			values = append(values, descendants...)
+		}
+	}
+
+	return values, nil
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case *jsonObject:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func isTruthy(value any) bool {
+	return value != nil && value != false
+}
+
+// compareJson compares two values in jq's order: null < false < true <
+// numbers < strings < arrays < objects
+func compareJson(a any, b any) int {
+	order := func(v any) int {
+		switch v := v.(type) {
+		case nil:
+			return 0
+		case bool:
+			if v {
+				return 2
+			}
+			return 1
+		case json.Number:
+			return 3
+		case string:
+			return 4
+		case []any:
+			return 5
+		default:
+			return 6
+		}
+	}
+
+	if order(a) != order(b) {
+		return order(a) - order(b)
+	}
+
+	switch a := a.(type) {
+	case json.Number:
+		x, _ := a.Float64()
+		y, _ := b.(json.Number).Float64()
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	default:
+		// arrays and objects are only compared for equality
+		x, _ := json.Marshal(a)
+		y, _ := json.Marshal(b)
+		return bytes.Compare(x, y)
+	}
+}
+
+func compareWith(op string, a any, b any) (bool, error) {
+	c := compareJson(a, b)
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+
+	return false, fmt.Errorf("unknown comparison: %s", op)
+}
+
+// queryParser holds the position in the query being parsed, both JSONPath
+// and jq expressions are parsed with it
+type queryParser struct {
+	query    string
+	pos      int
+	jsonPath bool
+}
+
+func (p *queryParser) errorf(format string, a ...any) error {
+	return fmt.Errorf("invalid query at column %d: %s", p.pos+1, fmt.Sprintf(format, a...))
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.query)
+}
+
+func (p *queryParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.query[p.pos]
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.done() && unicode.IsSpace(rune(p.query[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *queryParser) consume(s string) bool {
+	if strings.HasPrefix(p.query[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseIdentifier() string {
+	start := p.pos
+	for !p.done() {
+		c := p.query[p.pos]
+		if !(isAlpha(c) || isNum(c) || isUnderscore(c) || c == '-' || c == '$') {
+			break
+		}
+		p.pos++
+	}
+	return p.query[start:p.pos]
+}
+
+func (p *queryParser) parseInt() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for !p.done() && isNum(p.query[p.pos]) {
+		p.pos++
+	}
+
+	n, err := strconv.Atoi(p.query[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+func (p *queryParser) parseString() (string, error) {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		return "", p.errorf("expected a quoted string")
+	}
+
+	start := p.pos
+	p.pos++
+	for !p.done() && p.query[p.pos] != quote {
+		if p.query[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.done() {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos++
+
+	literal := p.query[start:p.pos]
+	if quote == '\'' {
+		literal = fmt.Sprintf("\"%s\"", strings.ReplaceAll(literal[1:len(literal)-1], "\"", "\\\""))
+	}
+
+	return strconv.Unquote(literal)
+}
+
+// parseLiteral parses a JSON scalar used on the right of a comparison
+func (p *queryParser) parseLiteral() (any, error) {
+	p.skipSpace()
+
+	switch {
+	case p.peek() == '"' || p.peek() == '\'':
+		return p.parseString()
+	case p.consume("true"):
+		return true, nil
+	case p.consume("false"):
+		return false, nil
+	case p.consume("null"):
+		return nil, nil
+	}
+
+	start := p.pos
+	for !p.done() && strings.IndexByte("+-.eE0123456789", p.query[p.pos]) != -1 {
+		p.pos++
+	}
+
+	number := json.Number(p.query[start:p.pos])
+	if _, err := number.Float64(); err != nil || start == p.pos {
+		p.pos = start
+		return nil, p.errorf("expected a string, number, true, false or null")
+	}
+
+	return number, nil
+}
+
+func (p *queryParser) parseComparisonOperator() string {
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// parseBracket parses the inside of [...] shared by JSONPath and jq: an index,
+// a slice, a quoted key or a wildcard. The opening bracket is already consumed,
+// the selector is built once the caller knows whether it is optional
+func (p *queryParser) parseBracket(allowWildcard bool, missing []any) (func(optional bool) selector, error) {
+	p.skipSpace()
+
+	if p.consume("]") {
+		return selectAll, nil
+	}
+
+	if allowWildcard && p.consume("*") {
+		p.skipSpace()
+		if !p.consume("]") {
+			return nil, p.errorf("expected ]")
+		}
+		return selectAll, nil
+	}
+
+	if p.peek() == '"' || p.peek() == '\'' {
+		keys := []string{}
+		for {
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+
+			p.skipSpace()
+			if !p.consume(",") {
+				break
+			}
+			p.skipSpace()
+		}
+		if !p.consume("]") {
+			return nil, p.errorf("expected ]")
+		}
+
+		return func(optional bool) selector {
+			return unionSelector(keys, func(key string) selector { return selectKey(key, optional, missing) })
+		}, nil
+	}
+
+	var start, end *int
+	n, ok := p.parseInt()
+	if ok {
+		start = &n
+	}
+
+	p.skipSpace()
+	if p.consume(":") {
+		p.skipSpace()
+		m, ok := p.parseInt()
+		if ok {
+			end = &m
+		}
+		p.skipSpace()
+		if !p.consume("]") {
+			return nil, p.errorf("expected ]")
+		}
+		return func(optional bool) selector { return selectSlice(start, end, optional, p.jsonPath) }, nil
+	}
+
+	if start == nil {
+		return nil, p.errorf("expected an index, slice, quoted key or *")
+	}
+
+	indices := []int{*start}
+	for p.consume(",") {
+		p.skipSpace()
+		i, ok := p.parseInt()
+		if !ok {
+			return nil, p.errorf("expected an index")
+		}
+		indices = append(indices, i)
+		p.skipSpace()
+	}
+	if !p.consume("]") {
+		return nil, p.errorf("expected ]")
+	}
+
+	return func(optional bool) selector {
+		return unionSelector(indices, func(i int) selector { return selectIndex(i, optional, missing) })
+	}, nil
+}
+
+func unionSelector[T any](members []T, newSelector func(T) selector) selector {
+	if len(members) == 1 {
+		return newSelector(members[0])
+	}
+
+	return func(value any) ([]any, error) {
+		values := []any{}
+		for _, member := range members {
+			selected, err := newSelector(member)(value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, selected...)
+		}
+		return values, nil
+	}
+}
+
+//=== JSONPath ===//
+
+// parseJsonPath parses a JSONPath expression such as `$.data[*].id`,
+// `$..name`, `$.items[0,2]`, `$.items[-2:]` or `$.items[?(@.price < 10)]`
+func parseJsonPath(query string) ([]selector, error) {
+	p := &queryParser{query: strings.TrimSpace(query), jsonPath: true}
+	if !p.consume("$") {
+		return nil, p.errorf("JSONPath must start with $")
+	}
+
+	return p.parseJsonPathSegments(")")
+}
+
+func (p *queryParser) parseJsonPathSegments(terminators string) ([]selector, error) {
+	selectors := []selector{}
+
+	for !p.done() {
+		if strings.IndexByte(terminators, p.peek()) != -1 || unicode.IsSpace(rune(p.peek())) {
+			break
+		}
+
+		switch {
+		case p.consume(".."):
+			selectors = append(selectors, selectDescendants)
+			if p.peek() == '[' {
+				continue
+			}
+
+			if p.consume("*") {
+				selectors = append(selectors, selectAll(true))
+				continue
+			}
+
+			key := p.parseIdentifier()
+			if key == "" {
+				return nil, p.errorf("expected a key after ..")
+			}
+			selectors = append(selectors, selectKey(key, true, jsonPathMissing))
+
+		case p.consume("."):
+			if p.consume("*") {
+				selectors = append(selectors, selectAll(true))
+				continue
+			}
+
+			key := p.parseIdentifier()
+			if key == "" {
+				return nil, p.errorf("expected a key after .")
+			}
+			selectors = append(selectors, selectKey(key, true, jsonPathMissing))
+
+		case p.consume("[?("):
+			filter, err := p.parseJsonPathFilter()
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, filter)
+
+		case p.consume("["):
+			bracket, err := p.parseBracket(true, jsonPathMissing)
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, bracket(true))
+
+		default:
+			return nil, p.errorf("unexpected character %q", p.peek())
+		}
+	}
+
+	return selectors, nil
+}
+
+// parseJsonPathFilter parses `@.path`, `@.path <op> <literal>` and the closing
+// `)]` of a filter, the `[?(` is already consumed
+func (p *queryParser) parseJsonPathFilter() (selector, error) {
+	p.skipSpace()
+	if !p.consume("@") {
+		return nil, p.errorf("filters must start with @")
+	}
+
+	path, err := p.parseJsonPathSegments(")=!<> ")
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.parseComparisonOperator()
+	var literal any
+	if op != "" {
+		literal, err = p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.skipSpace()
+	if !p.consume(")]") {
+		return nil, p.errorf("expected )]")
+	}
+
+	return func(value any) ([]any, error) {
+		children, _ := selectAll(true)(value)
+
+		matches := []any{}
+		for _, child := range children {
+			results, err := applySelectors(path, []any{child})
+			if err != nil || len(results) == 0 {
+				continue
+			}
+
+			matched := true
+			if op != "" {
+				matched, err = compareWith(op, results[0], literal)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if matched {
+				matches = append(matches, child)
+			}
+		}
+
+		return matches, nil
+	}, nil
+}
+
+//=== jq ===//
+
+// parseJq parses the supported subset of jq: paths like `.a.b[0]`, `.[]`,
+// `.[2:4]` and `."key"` with an optional `?`, pipes, and the `keys`,
+// `length`, `first`, `last`, `select(...)` and `map(...)` builtins
+func parseJq(query string) ([]selector, error) {
+	p := &queryParser{query: query}
+
+	selectors, err := p.parseJqPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.query[p.pos:])
+	}
+
+	return selectors, nil
+}
+
+func (p *queryParser) parseJqPipe() ([]selector, error) {
+	selectors := []selector{}
+
+	for {
+		term, err := p.parseJqTerm()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, term...)
+
+		p.skipSpace()
+		if !p.consume("|") {
+			return selectors, nil
+		}
+	}
+}
+
+func (p *queryParser) parseJqTerm() ([]selector, error) {
+	p.skipSpace()
+
+	if p.peek() == '.' {
+		return p.parseJqPath()
+	}
+
+	name := p.parseIdentifier()
+	switch name {
+	case "keys":
+		return []selector{jqKeys}, nil
+	case "length":
+		return []selector{jqLength}, nil
+	case "first":
+		return []selector{selectIndex(0, false, jqMissing)}, nil
+	case "last":
+		return []selector{selectIndex(-1, false, jqMissing)}, nil
+	case "select":
+		return p.parseJqSelect()
+	case "map":
+		return p.parseJqMap()
+	case "":
+		return nil, p.errorf("expected a path or builtin")
+	}
+
+	return nil, p.errorf("unsupported builtin: %s", name)
+}
+
+func (p *queryParser) parseJqPath() ([]selector, error) {
+	selectors := []selector{}
+
+	// a lone `.` is the identity
+	if p.consume(".") && (p.done() || strings.IndexByte(" |)=!<>", p.peek()) != -1) {
+		return selectors, nil
+	}
+
+	for {
+		var s selector
+
+		switch {
+		case p.consume("["):
+			bracket, err := p.parseBracket(false, jqMissing)
+			if err != nil {
+				return nil, err
+			}
+			s = bracket(p.consume("?"))
+
+		case p.peek() == '"':
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			s = selectKey(key, p.consume("?"), jqMissing)
+
+		default:
+			key := p.parseIdentifier()
+			if key == "" {
+				return nil, p.errorf("expected a key")
+			}
+			s = selectKey(key, p.consume("?"), jqMissing)
+		}
+
+		selectors = append(selectors, s)
+
+		if p.consume(".") {
+			continue
+		}
+		if p.peek() == '[' {
+			continue
+		}
+
+		return selectors, nil
+	}
+}
+
+func (p *queryParser) parseJqSelect() ([]selector, error) {
+	if !p.consume("(") {
+		return nil, p.errorf("expected ( after select")
+	}
+
+	condition, err := p.parseJqPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.parseComparisonOperator()
+	var literal any
+	if op != "" {
+		literal, err = p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.skipSpace()
+	if !p.consume(")") {
+		return nil, p.errorf("expected )")
+	}
+
+	return []selector{func(value any) ([]any, error) {
+		results, err := applySelectors(condition, []any{value})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			matched := isTruthy(result)
+			if op != "" {
+				matched, err = compareWith(op, result, literal)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if matched {
+				return []any{value}, nil
+			}
+		}
+
+		return []any{}, nil
+	}}, nil
+}
+
+func (p *queryParser) parseJqMap() ([]selector, error) {
+	if !p.consume("(") {
+		return nil, p.errorf("expected ( after map")
+	}
+
+	f, err := p.parseJqPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if !p.consume(")") {
+		return nil, p.errorf("expected )")
+	}
+
+	// map(f) is [.[] | f]
+	return []selector{func(value any) ([]any, error) {
+		results, err := applySelectors(append([]selector{selectAll(false)}, f...), []any{value})
+		if err != nil {
+			return nil, err
+		}
+		return []any{results}, nil
+	}}, nil
+}
+
+func jqKeys(value any) ([]any, error) {
+	switch v := value.(type) {
+	case *jsonObject:
+		keys := append([]string{}, v.keys...)
+		sort.Strings(keys)
+
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = key
+		}
+		return []any{values}, nil
+	case []any:
+		values := make([]any, len(v))
+		for i := range v {
+			values[i] = json.Number(strconv.Itoa(i))
+		}
+		return []any{values}, nil
+	}
+
+	return nil, fmt.Errorf("%s has no keys", jsonTypeName(value))
+}
+
+func jqLength(value any) ([]any, error) {
+	length := 0
+	switch v := value.(type) {
+	case nil:
+	case *jsonObject:
+		length = len(v.keys)
+	case []any:
+		length = len(v)
+	case string:
+		length = len([]rune(v))
+	case json.Number:
+		f, _ := v.Float64()
+		if f < 0 {
+			f = -f
+		}
+		return []any{json.Number(strconv.FormatFloat(f, 'f', -1, 64))}, nil
+	default:
+		return nil, fmt.Errorf("%s has no length", jsonTypeName(value))
+	}
+
+	return []any{json.Number(strconv.Itoa(length))}, nil
+}
+
+//=== output ===//
+
+// IsJsonPath reports whether a query is JSONPath rather than jq
+func IsJsonPath(query string) bool {
+	return strings.HasPrefix(strings.TrimSpace(query), "$")
+}
+
+// QueryJson runs a JSONPath (starting with `$`) or jq (starting with `.` or a
+// builtin) query against a JSON body and returns every result
+func QueryJson(body []byte, query string) ([]any, error) {
+	document, err := parseOrderedJson(body)
+	if err != nil {
+		return nil, err
+	}
+
+	parse := parseJq
+	if IsJsonPath(query) {
+		parse = parseJsonPath
+	}
+
+	selectors, err := parse(strings.TrimSpace(query))
+	if err != nil {
+		return nil, err
+	}
+
+	return applySelectors(selectors, []any{document})
+}
+
+// FormatQueryResults writes each result on its own line as JSON, JSONPath
+// matches are grouped in an array unless in raw mode. In raw mode strings are
+// written without quotes and everything else as compact JSON for use in shells
+func FormatQueryResults(results []any, jsonPath bool, raw bool) ([]byte, error) {
+	buffer := bytes.Buffer{}
+
+	if jsonPath && !raw {
+		results = []any{results}
+	}
+
+	for _, result := range results {
+		if s, ok := result.(string); ok && raw {
+			buffer.WriteString(s)
+			buffer.WriteString("\n")
+			continue
+		}
+
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if !raw {
+			encoder.SetIndent("", "  ")
+		}
+
+		err := encoder.Encode(result)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
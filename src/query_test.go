@@ -0,0 +1,213 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+const QUERY_TEST_DOCUMENT = `{
+  "store": {
+    "name": "corner shop",
+    "books": [
+      {"title": "Dune", "author": "Herbert", "price": 9.5, "tags": ["sf"]},
+      {"title": "Emma", "author": "Austen", "price": 4, "tags": []},
+      {"title": "Ubik", "author": "Dick", "price": 12, "tags": ["sf", "classic"], "isbn": "0-679"}
+    ],
+    "open": true,
+    "owner": null
+  },
+  "z": 1,
+  "a": 2
+}`
+
+// queryResults runs a query and writes the results the way -r does, one
+// compact result per line
+func queryResults(t *testing.T, query string) (string, error) {
+	t.Helper()
+
+	results, err := QueryJson([]byte(QUERY_TEST_DOCUMENT), query)
+	if err != nil {
+		return "", err
+	}
+
+	formatted, err := FormatQueryResults(results, IsJsonPath(query), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSuffix(string(formatted), "\n"), nil
+}
+
+func TestQueryJsonPath(t *testing.T) {
+	tests := []struct {
+		query   string
+		results string
+	}{
+		// fields and indexes
+		{"$", `{"store":{"name":"corner shop","books":[{"title":"Dune","author":"Herbert","price":9.5,"tags":["sf"]},{"title":"Emma","author":"Austen","price":4,"tags":[]},{"title":"Ubik","author":"Dick","price":12,"tags":["sf","classic"],"isbn":"0-679"}],"open":true,"owner":null},"z":1,"a":2}`},
+		{"$.store.name", "corner shop"},
+		{"$['store']['name']", "corner shop"},
+		{`$["store"].open`, "true"},
+		{"$.store.owner", "null"},
+		{"$.store.books[0].title", "Dune"},
+		{"$.store.books[-1].title", "Ubik"},
+		{"$.store.books[0,2].title", "Dune\nUbik"},
+
+		// wildcards and slices
+		{"$.store.books[*].author", "Herbert\nAusten\nDick"},
+		{"$.store.books.*.price", "9.5\n4\n12"},
+		{"$.store.books[1:].title", "Emma\nUbik"},
+		{"$.store.books[:1].title", "Dune"},
+		{"$.store.books[-2:].title", "Emma\nUbik"},
+
+		// recursion
+		{"$..isbn", "0-679"},
+		{"$..tags[0]", "sf\nsf"},
+		{"$.store..price", "9.5\n4\n12"},
+
+		// filters
+		{"$.store.books[?(@.price < 10)].title", "Dune\nEmma"},
+		{"$.store.books[?(@.author == 'Dick')].price", "12"},
+		{"$.store.books[?(@.isbn)].title", "Ubik"},
+
+		// missing keys and indexes match nothing
+		{"$.store.missing", ""},
+		{"$.store.books[5]", ""},
+		{"$.store.books[*].isbn", "0-679"},
+
+		// object keys keep the order of the document
+		{"$.*", `{"name":"corner shop","books":[{"title":"Dune","author":"Herbert","price":9.5,"tags":["sf"]},{"title":"Emma","author":"Austen","price":4,"tags":[]},{"title":"Ubik","author":"Dick","price":12,"tags":["sf","classic"],"isbn":"0-679"}],"open":true,"owner":null}` + "\n1\n2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results, err := queryResults(t, test.query)
+			if err != nil {
+				t.Fatalf("expected %q to run, got %s", test.query, err)
+			}
+
+			if results != test.results {
+				t.Errorf("expected\n%s\ngot\n%s", test.results, results)
+			}
+		})
+	}
+}
+
+func TestQueryJq(t *testing.T) {
+	tests := []struct {
+		query   string
+		results string
+	}{
+		// fields and indexes
+		{".", `{"store":{"name":"corner shop","books":[{"title":"Dune","author":"Herbert","price":9.5,"tags":["sf"]},{"title":"Emma","author":"Austen","price":4,"tags":[]},{"title":"Ubik","author":"Dick","price":12,"tags":["sf","classic"],"isbn":"0-679"}],"open":true,"owner":null},"z":1,"a":2}`},
+		{".store.name", "corner shop"},
+		{`."store"."name"`, "corner shop"},
+		{`.store["name"]`, "corner shop"},
+		{".store.books[0].title", "Dune"},
+		{".store.books[-1].title", "Ubik"},
+
+		// iterating and slices
+		{".store.books[].author", "Herbert\nAusten\nDick"},
+		{".store.books[1:].[].title", "Emma\nUbik"},
+		{".store.books[2:4] | length", "1"},
+		{".store.books[0].tags[:1]", `["sf"]`},
+
+		// missing keys and indexes are null
+		{".store.missing", "null"},
+		{".store.books[5]", "null"},
+		{".store.books[].isbn", "null\nnull\n0-679"},
+		{".store.name.first?", ""},
+
+		// pipes and builtins
+		{".store.books | length", "3"},
+		{".store.name | length", "11"},
+		{".store.owner | length", "0"},
+		{".store | keys", `["books","name","open","owner"]`},
+		{".store.books | map(.price)", "[9.5,4,12]"},
+		{".store.books | map(.tags | length)", "[1,0,2]"},
+		{".store.books[] | select(.price > 5) | .title", "Dune\nUbik"},
+		{".store.books | first | .author", "Herbert"},
+		{".store.books | last | .author", "Dick"},
+		{"keys", `["a","store","z"]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results, err := queryResults(t, test.query)
+			if err != nil {
+				t.Fatalf("expected %q to run, got %s", test.query, err)
+			}
+
+			if results != test.results {
+				t.Errorf("expected\n%s\ngot\n%s", test.results, results)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		// malformed expressions
+		{"$.store.books[", "invalid query at column 15: expected an index, slice, quoted key or *"},
+		{"$.store.books[?(@.price <)]", "invalid query at column 26: expected a string, number, true, false or null"},
+		{"$.store[name]", "invalid query at column 9: expected an index, slice, quoted key or *"},
+		{"$.store.books[0", "invalid query at column 16: expected ]"},
+		{`$['store`, "invalid query at column 9: unterminated string"},
+		{".store.", "invalid query at column 8: expected a key"},
+		{".store.books[", "invalid query at column 14: expected an index, slice, quoted key or *"},
+		{".store | ", "invalid query at column 9: expected a path or builtin"},
+		{"map(.price", "invalid query at column 11: expected )"},
+		{"select(.price >)", "invalid query at column 16: expected a string, number, true, false or null"},
+		{".store.books | reverse", "invalid query at column 23: unsupported builtin: reverse"},
+
+		// types that can't be indexed
+		{".store.name.first", `cannot index string with "first"`},
+		{".store.name[0]", "cannot index string with number"},
+		{".store.books.title", `cannot index array with "title"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results, err := queryResults(t, test.query)
+			if err == nil {
+				t.Fatalf("expected %q to fail, got %q", test.query, results)
+			}
+
+			if err.Error() != test.err {
+				t.Errorf("expected error %q, got %q", test.err, err)
+			}
+		})
+	}
+}
+
+func TestFormatQueryResults(t *testing.T) {
+	results := []any{"plain text", 1.5, nil, map[string]any{"a": "<b>"}}
+
+	tests := []struct {
+		name     string
+		jsonPath bool
+		raw      bool
+		output   string
+	}{
+		{"jq", false, false, "\"plain text\"\n1.5\nnull\n{\n  \"a\": \"<b>\"\n}\n"},
+		{"jq raw", false, true, "plain text\n1.5\nnull\n{\"a\":\"<b>\"}\n"},
+		{"jsonpath", true, false, "[\n  \"plain text\",\n  1.5,\n  null,\n  {\n    \"a\": \"<b>\"\n  }\n]\n"},
+		{"jsonpath raw", true, true, "plain text\n1.5\nnull\n{\"a\":\"<b>\"}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := FormatQueryResults(results, test.jsonPath, test.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(formatted) != test.output {
+				t.Errorf("expected\n%s\ngot\n%s", test.output, formatted)
+			}
+		})
+	}
+}
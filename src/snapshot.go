@@ -0,0 +1,272 @@
+package src
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Snapshot is the normalized form of a response saved with -snapshot and
+// compared against with -diff
+type Snapshot struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    any               `json:"body"`
+}
+
+// SnapshotPath is where the snapshot of a request file is kept, next to it
+func SnapshotPath(hurlFilePath string) string {
+	return hurlFilePath + ".snapshot.json"
+}
+
+// NewSnapshot normalizes a response so that runs can be compared, JSON
+// bodies are kept as JSON, text is prettified and binary bodies are hashed
+func NewSnapshot(res http.Response, body []byte, mediaType string, headerNames []string) (Snapshot, error) {
+	snapshot := Snapshot{
+		Status:  res.StatusCode,
+		Headers: make(map[string]string),
+	}
+
+	for _, name := range headerNames {
+		values := res.Header.Values(name)
+		if len(values) > 0 {
+			snapshot.Headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+		}
+	}
+
+	switch {
+	case len(body) == 0:
+		snapshot.Body = nil
+	case lexerForMediaType(mediaType) == "json":
+		parsed, err := parseOrderedJson(body)
+		if err != nil {
+			snapshot.Body = string(body)
+		} else {
+			snapshot.Body = parsed
+		}
+	case lexerForMediaType(mediaType) == "xml":
+		prettified, err := PrettifyXml(body)
+		if err != nil {
+			prettified = body
+		}
+		snapshot.Body = string(prettified)
+	case IsBinary(body, mediaType):
+		snapshot.Body = fmt.Sprintf("binary sha256:%x size:%d", sha256.Sum256(body), len(body))
+	default:
+		snapshot.Body = string(body)
+	}
+
+	return snapshot, nil
+}
+
+func WriteSnapshot(path string, snapshot Snapshot) error {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+func snapshotDocument(snapshot Snapshot) (any, error) {
+	snapshotBytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOrderedJson(snapshotBytes)
+}
+
+// SnapshotChange is a single difference between a snapshot and a response,
+// Old is missing for additions and New for removals
+type SnapshotChange struct {
+	Path   string
+	Old    any
+	New    any
+	Added  bool
+	Remove bool
+}
+
+// DiffSnapshot structurally compares the snapshot saved at path to the
+// current one, changes under any of the ignore paths are left out
+func DiffSnapshot(path string, current Snapshot, ignorePaths []string) ([]SnapshotChange, error) {
+	savedBytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no snapshot at %s, create one with -snapshot", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := parseOrderedJson(savedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+
+	currentDocument, err := snapshotDocument(current)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []SnapshotChange{}
+	diffJson("", saved, currentDocument, &changes)
+
+	kept := []SnapshotChange{}
+	for _, change := range changes {
+		if !matchesAnyPath(change.Path, ignorePaths) {
+			kept = append(kept, change)
+		}
+	}
+
+	return kept, nil
+}
+
+func diffJson(path string, old any, new any, changes *[]SnapshotChange) {
+	oldObject, oldIsObject := old.(*jsonObject)
+	newObject, newIsObject := new.(*jsonObject)
+	if oldIsObject && newIsObject {
+		for _, key := range oldObject.keys {
+			childPath := joinDiffPath(path, key)
+			newValue, exists := newObject.values[key]
+			if !exists {
+				*changes = append(*changes, SnapshotChange{Path: childPath, Old: oldObject.values[key], Remove: true})
+				continue
+			}
+			diffJson(childPath, oldObject.values[key], newValue, changes)
+		}
+
+		for _, key := range newObject.keys {
+			if _, exists := oldObject.values[key]; !exists {
+				*changes = append(*changes, SnapshotChange{Path: joinDiffPath(path, key), New: newObject.values[key], Added: true})
+			}
+		}
+		return
+	}
+
+	oldArray, oldIsArray := old.([]any)
+	newArray, newIsArray := new.([]any)
+	if oldIsArray && newIsArray {
+		for i := 0; i < max(len(oldArray), len(newArray)); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(newArray):
+				*changes = append(*changes, SnapshotChange{Path: childPath, Old: oldArray[i], Remove: true})
+			case i >= len(oldArray):
+				*changes = append(*changes, SnapshotChange{Path: childPath, New: newArray[i], Added: true})
+			default:
+				diffJson(childPath, oldArray[i], newArray[i], changes)
+			}
+		}
+		return
+	}
+
+	if jsonTypeName(old) != jsonTypeName(new) || compareJson(old, new) != 0 {
+		*changes = append(*changes, SnapshotChange{Path: path, Old: old, New: new})
+	}
+}
+
+func joinDiffPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+// matchesAnyPath reports whether a change path like body.items[2].id falls
+// under an ignore path, `*` and `[*]` match any key or index so
+// body.items[*].id ignores the id of every item
+func matchesAnyPath(path string, ignorePaths []string) bool {
+	segments := splitDiffPath(path)
+
+	for _, ignorePath := range ignorePaths {
+		ignoreSegments := splitDiffPath(ignorePath)
+		if len(ignoreSegments) > len(segments) {
+			continue
+		}
+
+		matched := true
+		for i, ignoreSegment := range ignoreSegments {
+			if ignoreSegment != "*" && ignoreSegment != "[*]" && ignoreSegment != segments[i] {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitDiffPath(path string) []string {
+	segments := []string{}
+	for _, dotted := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		key, indices, _ := strings.Cut(dotted, "[")
+		if key != "" {
+			segments = append(segments, key)
+		}
+
+		if indices != "" {
+			for _, index := range strings.Split("["+indices, "[")[1:] {
+				segments = append(segments, "["+index)
+			}
+		}
+	}
+
+	return segments
+}
+
+func formatDiffValue(value any) string {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(valueBytes)
+}
+
+// FormatSnapshotDiff shows removals in red, additions in green and changed
+// values in yellow, one change per line
+func FormatSnapshotDiff(changes []SnapshotChange) []byte {
+	buffer := bytes.Buffer{}
+
+	title := color.New(color.FgBlack, color.BgWhite).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if len(changes) == 0 {
+		buffer.WriteString(fmt.Sprintf("%s\n", title(" no differences from snapshot ")))
+		return buffer.Bytes()
+	}
+
+	buffer.WriteString(fmt.Sprintf("%s\n", title(fmt.Sprintf(" %d difference(s) from snapshot ", len(changes)))))
+
+	for _, change := range changes {
+		switch {
+		case change.Remove:
+			buffer.WriteString(red(fmt.Sprintf("- %s: %s", change.Path, formatDiffValue(change.Old))))
+		case change.Added:
+			buffer.WriteString(green(fmt.Sprintf("+ %s: %s", change.Path, formatDiffValue(change.New))))
+		default:
+			buffer.WriteString(fmt.Sprintf("%s %s: %s -> %s", yellow("~"), yellow(change.Path), red(formatDiffValue(change.Old)), green(formatDiffValue(change.New))))
+		}
+		buffer.WriteString("\n")
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,129 @@
+package src
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// bytes shown on a trace line before it's wrapped, like curl --trace-ascii
+const TRACE_LINE_WIDTH = 64
+
+// where -trace writes to, nil when tracing is off
+var traceOutput io.Writer
+var traceFile *os.File
+var traceMu sync.Mutex
+
+// OpenTrace starts tracing the bytes sent and received to stderr, or to the
+// file at path if there is one
+func OpenTrace(path string) error {
+	if path == "" {
+		traceOutput = os.Stderr
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	traceFile = file
+	traceOutput = file
+
+	return nil
+}
+
+func CloseTrace() error {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+
+	traceOutput = nil
+
+	if traceFile == nil {
+		return nil
+	}
+
+	err := traceFile.Close()
+	traceFile = nil
+
+	return err
+}
+
+func traceInfo(format string, a ...any) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+
+	if traceOutput == nil {
+		return
+	}
+
+	fmt.Fprintf(traceOutput, "== Info: %s\n", fmt.Sprintf(format, a...))
+}
+
+func traceTLSHandshake(state tls.ConnectionState) {
+	traceInfo("TLS handshake done, %s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+}
+
+// traceData writes data as lines of printable characters prefixed with their
+// offset, lines are broken after a newline or TRACE_LINE_WIDTH bytes
+func traceData(direction string, data []byte) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+
+	if traceOutput == nil || len(data) == 0 {
+		return
+	}
+
+	fmt.Fprintf(traceOutput, "%s %d bytes (0x%x)\n", direction, len(data), len(data))
+
+	line := make([]byte, 0, TRACE_LINE_WIDTH)
+	lineOffset := 0
+
+	for i, b := range data {
+		switch {
+		case b == '\r' || b == '\n':
+			// line endings are shown by breaking the line
+		case b < 0x20 || b >= 0x7f:
+			line = append(line, '.')
+		default:
+			line = append(line, b)
+		}
+
+		if b == '\n' || i-lineOffset+1 == TRACE_LINE_WIDTH || i == len(data)-1 {
+			fmt.Fprintf(traceOutput, "%04x: %s\n", lineOffset, line)
+			line = line[:0]
+			lineOffset = i + 1
+		}
+	}
+}
+
+// traceConn writes everything sent and received over a connection to the
+// trace output
+type traceConn struct {
+	net.Conn
+}
+
+func newTraceConn(conn net.Conn) net.Conn {
+	traceInfo("connected to %s", conn.RemoteAddr())
+	return &traceConn{Conn: conn}
+}
+
+func (c *traceConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	traceData("<= Recv", p[:n])
+	return n, err
+}
+
+func (c *traceConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	traceData("=> Send", p[:n])
+	return n, err
+}
+
+func (c *traceConn) Close() error {
+	traceInfo("closing connection to %s", c.RemoteAddr())
+	return c.Conn.Close()
+}
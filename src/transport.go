@@ -0,0 +1,194 @@
+package src
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// parseResolve turns a curl style `host:port:addr` into the `host:port` it
+// applies to and the `addr:port` to connect to instead
+func parseResolve(resolve string) (string, string, error) {
+	components := strings.SplitN(resolve, ":", 3)
+	if len(components) != 3 || components[0] == "" || components[1] == "" || components[2] == "" {
+		return "", "", fmt.Errorf("-resolve must be in the form host:port:addr, got: %s", resolve)
+	}
+
+	host, port := components[0], components[1]
+	addr := strings.TrimSuffix(strings.TrimPrefix(components[2], "["), "]")
+	if net.ParseIP(addr) == nil {
+		return "", "", fmt.Errorf("-resolve address must be an IP address, got: %s", addr)
+	}
+
+	return net.JoinHostPort(host, port), net.JoinHostPort(addr, port), nil
+}
+
+// parseConnectTo turns a curl style `host:port:connect-host:connect-port` into
+// the `host:port` it applies to and the `host:port` to connect to instead, IPv6
+// addresses are written in brackets like [::1]:443
+func parseConnectTo(connectTo string) (string, string, error) {
+	invalid := fmt.Errorf("-connect-to must be in the form host:port:connect-host:connect-port, got: %s", connectTo)
+
+	from, to, ok := splitAddressPair(connectTo)
+	if !ok {
+		return "", "", invalid
+	}
+
+	host, port, err := net.SplitHostPort(from)
+	if err != nil || host == "" || port == "" {
+		return "", "", invalid
+	}
+
+	connectHost, connectPort, err := net.SplitHostPort(to)
+	if err != nil {
+		return "", "", invalid
+	}
+
+	// an empty connect host or port keeps the one from the URL
+	if connectHost == "" {
+		connectHost = host
+	}
+	if connectPort == "" {
+		connectPort = port
+	}
+
+	return net.JoinHostPort(host, port), net.JoinHostPort(connectHost, connectPort), nil
+}
+
+// splitAddressPair splits `host:port:host:port` in two at the colon after the
+// first port, colons inside the brackets of an IPv6 address are skipped
+func splitAddressPair(pair string) (string, string, bool) {
+	colons := 0
+	inBrackets := false
+	for i, c := range pair {
+		switch {
+		case c == '[':
+			inBrackets = true
+		case c == ']':
+			inBrackets = false
+		case c == ':' && !inBrackets:
+			colons++
+			if colons == 2 {
+				return pair[:i], pair[i+1:], true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// NewDialContext returns a dialer that connects to the unix socket or mapped
+// address from the config in place of the one in the request URL. The URL is
+// left alone so the Host header and TLS server name stay the same
+func NewDialContext(config HurlConfig) DialContextFunc {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if config.UnixSocket != "" {
+			return dialer.DialContext(ctx, "unix", config.UnixSocket)
+		}
+
+		if target, exists := config.ConnectTo[addr]; exists {
+			addr = target
+		}
+
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// wrapConnections hands the transport's connections to wrap before they're
+// used, for looking at the bytes sent and received. TLS is done here rather
+// than by the transport to get at the decrypted bytes, and only HTTP/1.1 is
+// offered since HTTP/2 frames are binary and its headers compressed
+func wrapConnections(transport *http.Transport, dialContext DialContextFunc, wrap func(net.Conn) net.Conn) {
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return wrap(conn), nil
+	}
+
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+
+		tlsConfig := &tls.Config{}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+		}
+		tlsConfig.ServerName = host
+		tlsConfig.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		traceTLSHandshake(tlsConn.ConnectionState())
+
+		return wrap(tlsConn), nil
+	}
+}
+
+// newTransport is the transport requests are sent with before anything looks
+// at the connections, HTTP/2 is used when the server offers it
+func newTransport(config HurlConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = NewDialContext(config)
+
+	// a proxy would be connected to through the socket as well
+	if config.UnixSocket != "" {
+		transport.Proxy = nil
+	}
+
+	if config.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return transport
+}
+
+func NewHttpClient(config HurlConfig) *http.Client {
+	transport := newTransport(config)
+
+	if headerOrder == "wire" || traceOutput != nil {
+		wrapConnections(transport, NewDialContext(config), func(conn net.Conn) net.Conn {
+			if headerOrder == "wire" {
+				conn = &headerOrderConn{Conn: conn, recorder: &wireHeaderOrder}
+			}
+
+			if traceOutput != nil {
+				conn = newTraceConn(conn)
+			}
+
+			return conn
+		})
+	}
+
+	if harRecording != nil {
+		return &http.Client{Transport: &harTransport{transport}}
+	}
+
+	return &http.Client{Transport: transport}
+}
@@ -1,7 +1,12 @@
 package src
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
 // Set data structure helpers
@@ -9,7 +14,62 @@ type void struct{}
 
 var member void
 
+// when set, warnings are kept in Warnings instead of being printed so they can
+// be reported in machine readable output
+var CollectWarnings bool
+var Warnings []string
+
 func PrintWarning(err error) {
-	warning := color.New(color.Bold, color.FgYellow).PrintfFunc()
-	warning("warning: %s\n", err.Error())
+	if CollectWarnings {
+		Warnings = append(Warnings, strings.TrimSpace(err.Error()))
+		return
+	}
+
+	warning := color.New(color.Bold, color.FgYellow).FprintfFunc()
+	warning(os.Stderr, "warning: %s\n", err.Error())
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe
+// or a file
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// ConfigureColor turns coloured output on or off, "auto" colours output only
+// when stdout is a terminal and NO_COLOR isn't set to a non-empty value
+func ConfigureColor(mode string) {
+	switch mode {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		color.NoColor = os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !isTerminal(os.Stdout)
+	}
+}
+
+// countingWriter discards everything written to it but keeps track of how many
+// bytes it has seen
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for i := n / unit; i >= unit; i /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,195 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gorilla/websocket"
+)
+
+// headers that gorilla/websocket sets itself during the handshake
+var websocketHandshakeHeaders = map[string]void{
+	"Upgrade":                  member,
+	"Connection":               member,
+	"Sec-Websocket-Key":        member,
+	"Sec-Websocket-Version":    member,
+	"Sec-Websocket-Extensions": member,
+}
+
+func websocketURL(h *HurlFile) string {
+	u := h.URL
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
+	return u.String()
+}
+
+func FormatWebSocketFrame(directionCharacter string, messageType int, message []byte) []byte {
+	timestamp := color.New(color.FgHiBlack).Sprintf("[%s]", time.Now().Format("15:04:05.000"))
+
+	if messageType == websocket.BinaryMessage {
+		return []byte(fmt.Sprintf("%s %s binary frame (%s)\n", timestamp, directionCharacter, formatBytes(int64(len(message)))))
+	}
+
+	body := message
+	if json.Valid(message) {
+		formatted, err := FormatBody(message, "application/json")
+		if err == nil && len(formatted) > 0 {
+			body = bytes.TrimRight(formatted, "\n")
+		}
+	}
+
+	return []byte(fmt.Sprintf("%s %s %s\n", timestamp, directionCharacter, body))
+}
+
+// RunWebSocket performs the upgrade handshake described by the request file,
+// sends the messages in its body and prints every frame received until the
+// connection closes or goes idle
+func (h HurlOutput) RunWebSocket(hurlFile *HurlFile) error {
+	header := http.Header{}
+	for name, val := range hurlFile.Headers {
+		if _, skip := websocketHandshakeHeaders[http.CanonicalHeaderKey(name)]; skip {
+			continue
+		}
+		header.Set(name, val)
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = NewDialContext(h.Config)
+	if h.Config.Insecure {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	conn, res, err := dialer.Dial(websocketURL(hurlFile), header)
+	if err != nil {
+		if res != nil {
+			return fmt.Errorf("websocket handshake failed with %s: %w", res.Status, err)
+		}
+		return err
+	}
+	defer conn.Close()
+
+	if h.Config.Verbose {
+		buffer := bytes.Buffer{}
+		buffer.WriteString(FormatStatusLine(*res))
+		buffer.Write(FormatHeaders(res.Header, "<", nil))
+		fmt.Printf("%s\n", buffer.String())
+	}
+
+	// closed when the session ends so the goroutines below don't block
+	// forever on a send nothing is waiting for
+	done := make(chan struct{})
+	defer close(done)
+
+	received := make(chan error)
+	go func() {
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err == nil {
+				fmt.Printf("%s", FormatWebSocketFrame("<", messageType, message))
+			}
+
+			select {
+			case received <- err:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, message := range hurlFile.WebSocketMessages {
+		err := conn.WriteMessage(websocket.TextMessage, message)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s", FormatWebSocketFrame(">", websocket.TextMessage, message))
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// in interactive mode the session stays open until stdin closes, after
+	// that it ends once the server has gone quiet
+	var stdinLines chan string
+	var idle <-chan time.Time
+	if h.Config.Interactive {
+		stdinLines = make(chan string)
+		go func() {
+			sc := bufio.NewScanner(os.Stdin)
+			for sc.Scan() {
+				select {
+				case stdinLines <- sc.Text():
+				case <-done:
+					return
+				}
+			}
+			close(stdinLines)
+		}()
+	} else {
+		idle = time.After(h.Config.WebSocketTimeout)
+	}
+
+	for {
+		select {
+		case err := <-received:
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return nil
+				}
+				return err
+			}
+
+			if stdinLines == nil {
+				idle = time.After(h.Config.WebSocketTimeout)
+			}
+
+		case line, ok := <-stdinLines:
+			if !ok {
+				stdinLines = nil
+				idle = time.After(h.Config.WebSocketTimeout)
+				continue
+			}
+
+			err := conn.WriteMessage(websocket.TextMessage, []byte(line))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s", FormatWebSocketFrame(">", websocket.TextMessage, []byte(line)))
+
+		case <-idle:
+			return closeWebSocket(conn)
+
+		case <-interrupt:
+			return closeWebSocket(conn)
+		}
+	}
+}
+
+func closeWebSocket(conn *websocket.Conn) error {
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	err := conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// the goroutine reading frames has to stop once the session is over, nothing
+// is left to take what it read
+func TestRunWebSocketStopsReading(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, message)
+		}
+	}))
+	defer server.Close()
+
+	src := "WS " + strings.Replace(server.URL, "http://", "ws://", 1) + "\n\nhello\n"
+	hurlFile, err := ParseHurlFile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hurlOutput := HurlOutput{Config: HurlConfig{WebSocketTimeout: 50 * time.Millisecond}}
+	err = hurlOutput.RunWebSocket(hurlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stacks := ""
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		buffer := make([]byte, 1<<20)
+		stacks = string(buffer[:runtime.Stack(buffer, true)])
+		if !strings.Contains(stacks, "RunWebSocket.func") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("goroutines started by RunWebSocket were left running:\n%s", stacks)
+}